@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync/atomic"
 
 	"github.com/kubescape/go-logger"
@@ -38,6 +39,23 @@ func initHTTPHandlers() {
 	http.HandleFunc("/cloudproviderinfo", cloudProviderHandler)
 	http.HandleFunc("/version", versionHandler)
 	http.HandleFunc("/cniinfo", CNIHandler)
+	http.HandleFunc("/unixsockets", unixSocketsHandler)
+	http.HandleFunc("/firewallrules", firewallRulesHandler)
+	http.HandleFunc("/networkinterfaces", networkInterfacesHandler)
+	http.HandleFunc("/componentendpoints", componentEndpointsHandler)
+	http.HandleFunc("/activeprobes", activeProbesHandler)
+	http.HandleFunc("/connections", connectionsHandler)
+	http.HandleFunc("/kernelmodules", kernelModulesHandler)
+	http.HandleFunc("/sysctls", sysctlsHandler)
+	http.HandleFunc("/bootparams", bootParamsHandler)
+	http.HandleFunc("/mounts", mountsHandler)
+	http.HandleFunc("/accounts", accountsHandler)
+	http.HandleFunc("/sshd", sshdHandler)
+	http.HandleFunc("/sudoers", sudoersHandler)
+	http.HandleFunc("/audit", auditHandler)
+	http.HandleFunc("/packages", packagesHandler)
+	http.HandleFunc("/systemdunits", systemdUnitsHandler)
+	http.HandleFunc("/scheduledtasks", scheduledTasksHandler)
 
 }
 
@@ -125,15 +143,116 @@ func kubeletInfoHandler(rw http.ResponseWriter, r *http.Request) {
 }
 
 func LinuxKernelVariablesHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseKernelVariables(r.Context())
+	resp, err := sensor.SenseKernelVariables(r.Context(), sensor.NewSysctlFilter(r.URL.Query()))
 	GenericSensorHandler(rw, r, resp, err, "SenseKernelVariables")
 }
 
 func openedPortsHandler(rw http.ResponseWriter, r *http.Request) {
+	// `?allNetNs=true` adds the listening sockets of every network namespace on the node
+	if allNetNs, _ := strconv.ParseBool(r.URL.Query().Get("allNetNs")); allNetNs {
+		resp, err := sensor.SenseOpenPortsAllNetNs(r.Context())
+		GenericSensorHandler(rw, r, resp, err, "SenseOpenPortsAllNetNs")
+		return
+	}
 	resp, err := sensor.SenseOpenPorts(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "SenseOpenPorts")
 }
 
+func unixSocketsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseUnixSockets(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseUnixSockets")
+}
+
+func firewallRulesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseFirewallRules(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseFirewallRules")
+}
+
+func networkInterfacesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseNetworkInterfaces(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseNetworkInterfaces")
+}
+
+func componentEndpointsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseComponentEndpoints(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseComponentEndpoints")
+}
+
+func activeProbesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseActiveProbes(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseActiveProbes")
+}
+
+func connectionsHandler(rw http.ResponseWriter, r *http.Request) {
+	query, err := sensor.ParseConnectionsQuery(r.URL.Query())
+	if err != nil {
+		GenericSensorHandler(rw, r, nil, err, "SenseConnections")
+		return
+	}
+	resp, err := sensor.SenseConnections(r.Context(), query)
+	GenericSensorHandler(rw, r, resp, err, "SenseConnections")
+}
+
+func kernelModulesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKernelModules(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKernelModules")
+}
+
+func sysctlsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseSysctls(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseSysctls")
+}
+
+func bootParamsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseBootParams(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseBootParams")
+}
+
+func mountsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseMounts(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseMounts")
+}
+
+func accountsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseAccounts(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseAccounts")
+}
+
+func sshdHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseSSHD(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseSSHD")
+}
+
+func sudoersHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseSudoers(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseSudoers")
+}
+
+func auditHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseAudit(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseAudit")
+}
+
+func packagesHandler(rw http.ResponseWriter, r *http.Request) {
+	format, err := sensor.ParseSBOMFormat(r.URL.Query())
+	if err != nil {
+		GenericSensorHandler(rw, r, nil, err, "SensePackages")
+		return
+	}
+	resp, err := sensor.SensePackages(r.Context(), format)
+	GenericSensorHandler(rw, r, resp, err, "SensePackages")
+}
+
+func systemdUnitsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseSystemdUnits(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseSystemdUnits")
+}
+
+func scheduledTasksHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseScheduledTasks(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseScheduledTasks")
+}
+
 func osReleaseHandler(rw http.ResponseWriter, r *http.Request) {
 	fileContent, err := sensor.SenseOsRelease()
 	if err != nil {
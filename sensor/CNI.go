@@ -2,7 +2,12 @@ package sensor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
 
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
@@ -10,48 +15,142 @@ import (
 	"github.com/kubescape/host-scanner/sensor/internal/utils"
 )
 
+// CNI config file extensions which are loaded by the container runtimes
+var cniConfigExtensions = []string{".conf", ".conflist", ".json"}
+
+// CNI plugin types which enforce NetworkPolicy
+var cniNetworkPolicyPluginTypes = []string{"calico", "cilium-cni", "antrea"}
+
+// CNI plugin types of Multus
+var cniMultusPluginTypes = []string{"multus", "multus-shim"}
+
 // KubeProxyInfo holds information about kube-proxy process
 type CNIInfo struct {
 	CNIConfigFiles []*ds.FileInfo `json:"CNIConfigFiles,omitempty"`
 
-	// The name of the running CNI
+	// The parsed CNI network configurations, in the order the container runtime loads them
+	CNIConfigs []CNIConfig `json:"CNIConfigs,omitempty"`
+
+	// The names of the running CNIs, as detected from their processes
 	CNINames []string `json:"CNINames,omitempty"`
+
+	// The CNIs detected from the running processes, the plugin binaries and the CNI config files
+	CNIs []CNIDetection `json:"CNIs,omitempty"`
+}
+
+// CNIConfig holds a parsed CNI network configuration (.conf or .conflist) file
+type CNIConfig struct {
+	// The path of the file
+	// Example: /etc/cni/net.d/10-calico.conflist
+	Path string `json:"path"`
+
+	// The network name
+	Name string `json:"name"`
+
+	CNIVersion string `json:"cniVersion"`
+
+	// The plugin chain, in execution order
+	Plugins []CNIPlugin `json:"plugins"`
+
+	// true for the config which the container runtime picks (the first valid file in lexicographic order)
+	Active bool `json:"active"`
+
+	// true if the chain contains a plugin which enforces NetworkPolicy (calico, cilium, antrea)
+	NetworkPolicySupport bool `json:"networkPolicySupport"`
+
+	// true if the chain contains the bandwidth plugin (traffic shaping)
+	Bandwidth bool `json:"bandwidth"`
+
+	// true if the chain contains the portmap plugin (hostPort support)
+	PortMap bool `json:"portMap"`
+
+	// true if the chain is a Multus meta plugin which delegates to other networks
+	MultusDelegated bool `json:"multusDelegated"`
+
+	// Error parsing the file, if any
+	Err string `json:"err,omitempty"`
+}
+
+// CNIPlugin holds a plugin of a CNI chain
+type CNIPlugin struct {
+	// The plugin type (the binary name)
+	// Example: calico, portmap
+	Type string `json:"type"`
+
+	// Capabilities enabled for the plugin
+	// Example: {"portMappings": true}
+	Capabilities map[string]bool `json:"capabilities,omitempty"`
+
+	// Plugins of the networks delegated by a Multus plugin
+	Delegates []CNIPlugin `json:"delegates,omitempty"`
+}
+
+// cniNetConf is the subset of a CNI network configuration (or network configuration list) which is of interest
+type cniNetConf struct {
+	CNIVersion   string          `json:"cniVersion"`
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	Capabilities map[string]bool `json:"capabilities"`
+	Plugins      []cniNetConf    `json:"plugins"`
+
+	// Multus delegates
+	Delegates []cniNetConf `json:"delegates"`
 }
 
 // SenseCNIInfo return `CNIInfo`
 func SenseCNIInfo(ctx context.Context) (*CNIInfo, error) {
-	var err error
 	ret := CNIInfo{}
 
-	// make cni config files
-	CNIConfigInfo, err := makeCNIConfigFilesInfo(ctx)
-
+	CNIConfigDir, err := getCNIConfigDir(ctx)
 	if err != nil {
 		logger.L().Ctx(ctx).Warning("SenseCNIInfo", helpers.Error(err))
 	} else {
-		ret.CNIConfigFiles = CNIConfigInfo
+		// make cni config files
+		CNIConfigInfo, err := makeCNIConfigFilesInfo(ctx, CNIConfigDir)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("SenseCNIInfo", helpers.Error(err))
+		} else {
+			ret.CNIConfigFiles = CNIConfigInfo
+		}
+
+		// parse cni config files
+		ret.CNIConfigs, err = parseCNIConfigDir(utils.HostPath(CNIConfigDir), CNIConfigDir)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("SenseCNIInfo", helpers.Error(err))
+		}
 	}
 
+	// detect CNIs
+	binDirs := make([]string, 0, len(cniBinDirs))
+	for _, dir := range cniBinDirs {
+		binDirs = append(binDirs, utils.HostPath(dir))
+	}
+	ret.CNIs = detectCNIs(ctx, procDirName, binDirs, ret.CNIConfigs)
+
 	// get CNI name
-	ret.CNINames = getCNINames(ctx)
+	ret.CNINames = getCNINames(ctx, ret.CNIs)
 
 	return &ret, nil
 }
 
-// makeCNIConfigFilesInfo - returns a list of FileInfos of cni config files.
-func makeCNIConfigFilesInfo(ctx context.Context) ([]*ds.FileInfo, error) {
-	// *** Start handling CNI Files
+// getCNIConfigDir - returns the CNI config dir of the container runtime.
+func getCNIConfigDir(ctx context.Context) (string, error) {
 	kubeletProc, err := LocateKubeletProcess()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	CNIConfigDir := utils.GetCNIConfigPath(ctx, kubeletProc)
 
 	if CNIConfigDir == "" {
-		return nil, fmt.Errorf("no CNI Config dir found in getCNIConfigPath")
+		return "", fmt.Errorf("no CNI Config dir found in getCNIConfigPath")
 	}
 
+	return CNIConfigDir, nil
+}
+
+// makeCNIConfigFilesInfo - returns a list of FileInfos of cni config files.
+func makeCNIConfigFilesInfo(ctx context.Context, CNIConfigDir string) ([]*ds.FileInfo, error) {
 	//Getting CNI config files
 	CNIConfigInfo, err := makeHostDirFilesInfoVerbose(ctx, CNIConfigDir, true, nil, 0)
 
@@ -67,35 +166,110 @@ func makeCNIConfigFilesInfo(ctx context.Context) ([]*ds.FileInfo, error) {
 	return CNIConfigInfo, nil
 }
 
-// getCNIName - looking for CNI process and return CNI name, or empty if not found.
-func getCNINames(ctx context.Context) []string {
-	var CNIs []string
-	supportedCNIs := []struct {
-		name          string
-		processSuffix string
-	}{
-		{"aws", "aws-k8s-agent"}, // aws VPC CNI agent
-		// 'canal' CNI "sets up Calico to handle policy management and Flannel to manage the network itself". Therefore, we will first
-		// check "calico" (which supports network policies and indicates for either 'canal' or 'calico') and then flannel.
-		{"Calico", "calico-node"},
-		{"Flannel", "flanneld"},
-		{"Cilium", "cilium-agent"},
-		{"WeaveNet", "weave-net"},
-		{"Kindnet", "kindnetd"},
-		{"Multus", "multus"},
+// parseCNIConfigDir parses the CNI config files at `dir`, which is reported as `reportedDir`.
+// The files are sorted like the container runtimes load them, and the first valid one is marked as active.
+func parseCNIConfigDir(dir, reportedDir string) ([]CNIConfig, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CNI config dir %s: %w", reportedDir, err)
 	}
 
-	for _, cni := range supportedCNIs {
-		p, _ := utils.LocateProcessByExecSuffix(cni.processSuffix)
+	var fileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, ext := range cniConfigExtensions {
+			if path.Ext(entry.Name()) == ext {
+				fileNames = append(fileNames, entry.Name())
+				break
+			}
+		}
+	}
+	sort.Strings(fileNames)
 
-		if p != nil {
-			logger.L().Debug("CNI process found", helpers.String("name", cni.name))
-			CNIs = append(CNIs, cni.name)
+	res := make([]CNIConfig, 0, len(fileNames))
+	hasActive := false
+	for _, fileName := range fileNames {
+		conf := CNIConfig{Path: path.Join(reportedDir, fileName), Plugins: []CNIPlugin{}}
+		content, err := os.ReadFile(path.Join(dir, fileName))
+		if err == nil {
+			err = parseCNIConfig(content, &conf)
+		}
+		if err != nil {
+			conf.Err = err.Error()
+		} else if !hasActive && len(conf.Plugins) > 0 {
+			conf.Active = true
+			hasActive = true
+		}
+		res = append(res, conf)
+	}
+
+	return res, nil
+}
+
+// parseCNIConfig parses a CNI network configuration (.conf) or network configuration list (.conflist) into `conf`
+func parseCNIConfig(content []byte, conf *CNIConfig) error {
+	netConf := cniNetConf{}
+	if err := json.Unmarshal(content, &netConf); err != nil {
+		return fmt.Errorf("failed to parse CNI config: %w", err)
+	}
+	conf.Name = netConf.Name
+	conf.CNIVersion = netConf.CNIVersion
+	conf.Plugins = cniPluginChain(&netConf)
+
+	for _, plugin := range conf.Plugins {
+		if slices.Contains(cniMultusPluginTypes, plugin.Type) {
+			conf.MultusDelegated = true
+		}
+		// the capabilities of a Multus chain are those of the delegated networks
+		for _, p := range append([]CNIPlugin{plugin}, plugin.Delegates...) {
+			switch {
+			case slices.Contains(cniNetworkPolicyPluginTypes, p.Type):
+				conf.NetworkPolicySupport = true
+			case p.Type == "bandwidth":
+				conf.Bandwidth = true
+			case p.Type == "portmap":
+				conf.PortMap = true
+			}
+		}
+	}
+	return nil
+}
+
+// cniPluginChain returns the plugins of a network configuration (list)
+func cniPluginChain(netConf *cniNetConf) []CNIPlugin {
+	netConfs := netConf.Plugins
+	if len(netConfs) == 0 && netConf.Type != "" {
+		// a single network configuration is a chain of one plugin
+		netConfs = []cniNetConf{*netConf}
+	}
+
+	res := make([]CNIPlugin, 0, len(netConfs))
+	for i := range netConfs {
+		plugin := CNIPlugin{
+			Type:         netConfs[i].Type,
+			Capabilities: netConfs[i].Capabilities,
+		}
+		for j := range netConfs[i].Delegates {
+			plugin.Delegates = append(plugin.Delegates, cniPluginChain(&netConfs[i].Delegates[j])...)
+		}
+		res = append(res, plugin)
+	}
+	return res
+}
+
+// getCNINames - returns the names of the running CNIs, or empty if not found.
+func getCNINames(ctx context.Context, detections []CNIDetection) []string {
+	var CNIs []string
+	for _, detection := range detections {
+		if detection.Confidence == CNIConfidenceHigh {
+			CNIs = append(CNIs, detection.Name)
 		}
 	}
 
 	if len(CNIs) == 0 {
-		logger.L().Warning("No CNI found")
+		logger.L().Ctx(ctx).Warning("No CNI found")
 	}
 
 	return CNIs
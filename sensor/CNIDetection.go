@@ -0,0 +1,214 @@
+package sensor
+
+import (
+	"context"
+	"debug/buildinfo"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+const (
+	// CNI detection signals
+	CNISignalProcess = "process"
+	CNISignalBinary  = "binary"
+	CNISignalConfig  = "config"
+
+	// CNI detection confidence levels
+	CNIConfidenceHigh   = "high"
+	CNIConfidenceMedium = "medium"
+	CNIConfidenceLow    = "low"
+)
+
+// Default directories of the CNI plugin binaries
+var cniBinDirs = []string{
+	"/opt/cni/bin",
+	"/home/kubernetes/bin", // GKE
+}
+
+// cniSignature describes how a CNI is detected on the node
+type cniSignature struct {
+	name string
+
+	// suffixes of the executables of the CNI agents
+	processSuffixes []string
+
+	// names of the CNI plugin binaries, in the CNI bin dir
+	binaries []string
+
+	// CNI plugin types in the CNI config files
+	pluginTypes []string
+}
+
+var cniSignatures = []cniSignature{
+	{name: "aws", processSuffixes: []string{"/aws-k8s-agent"}, binaries: []string{"aws-cni"}, pluginTypes: []string{"aws-cni"}}, // aws VPC CNI agent
+	// 'canal' CNI "sets up Calico to handle policy management and Flannel to manage the network itself". Therefore, we will
+	// report both "calico" (which supports network policies and indicates for either 'canal' or 'calico') and flannel.
+	{name: "Calico", processSuffixes: []string{"/calico-node"}, binaries: []string{"calico"}, pluginTypes: []string{"calico"}},
+	// the flannel plugin binary is part of the reference plugins, so it doesn't indicate flannel is in use
+	{name: "Flannel", processSuffixes: []string{"/flanneld"}, pluginTypes: []string{"flannel"}},
+	{name: "Cilium", processSuffixes: []string{"/cilium-agent"}, binaries: []string{"cilium-cni"}, pluginTypes: []string{"cilium-cni"}},
+	{name: "WeaveNet", processSuffixes: []string{"/weave-net", "/weaver"}, binaries: []string{"weave-net"}, pluginTypes: []string{"weave-net"}},
+	{name: "Kindnet", processSuffixes: []string{"/kindnetd"}},
+	{name: "Multus", processSuffixes: []string{"/multus", "/multus-daemon"}, binaries: []string{"multus", "multus-shim"}, pluginTypes: cniMultusPluginTypes},
+	{name: "Antrea", processSuffixes: []string{"/antrea-agent"}, binaries: []string{"antrea"}, pluginTypes: []string{"antrea"}},
+	{name: "Kube-OVN", processSuffixes: []string{"/kube-ovn-daemon"}, binaries: []string{"kube-ovn"}, pluginTypes: []string{"kube-ovn"}},
+	{name: "OVN-Kubernetes", processSuffixes: []string{"/ovnkube"}, binaries: []string{"ovn-k8s-cni-overlay"}, pluginTypes: []string{"ovn-k8s-cni-overlay"}},
+	{name: "kube-router", processSuffixes: []string{"/kube-router"}},
+	// GKE dataplane v2 runs cilium as `anetd`
+	{name: "GKE-Dataplane-V2", processSuffixes: []string{"/anetd"}},
+}
+
+// CNIDetection holds a CNI which was detected on the node
+type CNIDetection struct {
+	// Example: Calico
+	Name string `json:"name"`
+
+	// The signals the CNI was detected by: process, binary, config
+	Signals []string `json:"signals"`
+
+	// high: the CNI agent is running.
+	// medium: the CNI is in the active CNI config.
+	// low: only a plugin binary or an inactive CNI config was found.
+	Confidence string `json:"confidence"`
+
+	// The version of the CNI, read from the Go build info of its agent or plugin binary (if found)
+	// Example: v3.27.0
+	Version string `json:"version,omitempty"`
+
+	// The PID of the CNI agent (if running)
+	PID int32 `json:"pid,omitempty"`
+}
+
+// goBinaryVersion returns the main module version of a Go binary, or empty if it was not stamped
+func goBinaryVersion(binPath string) (string, error) {
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil {
+		return "", err
+	}
+	if info.Main.Version == "(devel)" {
+		return "", nil
+	}
+	return info.Main.Version, nil
+}
+
+// findCNIProcesses returns the PID of the agent of each running CNI
+func findCNIProcesses(procDir string) map[string]int32 {
+	var suffixes []string
+	for _, sig := range cniSignatures {
+		suffixes = append(suffixes, sig.processSuffixes...)
+	}
+	pids := locateProcessesBySuffix(procDir, suffixes)
+
+	res := map[string]int32{}
+	for _, sig := range cniSignatures {
+		for _, suffix := range sig.processSuffixes {
+			if pid, ok := pids[suffix]; ok {
+				res[sig.name] = pid
+				break
+			}
+		}
+	}
+	return res
+}
+
+// detectCNIs detects the CNIs on the node from the running processes under `procDir`,
+// the plugin binaries under `binDirs` and the parsed CNI `configs`
+func detectCNIs(ctx context.Context, procDir string, binDirs []string, configs []CNIConfig) []CNIDetection {
+	processes := findCNIProcesses(procDir)
+
+	// plugin types of the active and inactive configs
+	activeTypes, inactiveTypes := map[string]bool{}, map[string]bool{}
+	for _, conf := range configs {
+		types := inactiveTypes
+		if conf.Active {
+			types = activeTypes
+		}
+		for _, plugin := range conf.Plugins {
+			for _, p := range append([]CNIPlugin{plugin}, plugin.Delegates...) {
+				types[p.Type] = true
+			}
+		}
+	}
+
+	res := make([]CNIDetection, 0)
+	for _, sig := range cniSignatures {
+		detection := CNIDetection{Name: sig.name, Signals: []string{}}
+		var versionPaths []string
+
+		if pid, ok := processes[sig.name]; ok {
+			detection.Signals = append(detection.Signals, CNISignalProcess)
+			detection.Confidence = CNIConfidenceHigh
+			detection.PID = pid
+			versionPaths = append(versionPaths, path.Join(procDir, strconv.Itoa(int(pid)), "exe"))
+		}
+
+		for _, binary := range sig.binaries {
+			for _, dir := range binDirs {
+				binPath := path.Join(dir, binary)
+				if info, err := os.Stat(binPath); err != nil || !info.Mode().IsRegular() {
+					continue
+				}
+				if !slices.Contains(detection.Signals, CNISignalBinary) {
+					detection.Signals = append(detection.Signals, CNISignalBinary)
+				}
+				versionPaths = append(versionPaths, binPath)
+			}
+		}
+		if detection.Confidence == "" && len(detection.Signals) > 0 {
+			detection.Confidence = CNIConfidenceLow
+		}
+
+		for _, pluginType := range sig.pluginTypes {
+			if !activeTypes[pluginType] && !inactiveTypes[pluginType] {
+				continue
+			}
+			if !slices.Contains(detection.Signals, CNISignalConfig) {
+				detection.Signals = append(detection.Signals, CNISignalConfig)
+			}
+			if activeTypes[pluginType] && detection.Confidence != CNIConfidenceHigh {
+				detection.Confidence = CNIConfidenceMedium
+			} else if detection.Confidence == "" {
+				detection.Confidence = CNIConfidenceLow
+			}
+		}
+
+		if len(detection.Signals) == 0 {
+			continue
+		}
+		for _, versionPath := range versionPaths {
+			version, err := goBinaryVersion(versionPath)
+			if err != nil {
+				logger.L().Debug("failed to read CNI build info", helpers.String("path", versionPath), helpers.Error(err))
+				continue
+			}
+			if version != "" {
+				detection.Version = version
+				break
+			}
+		}
+		logger.L().Ctx(ctx).Debug("CNI found", helpers.String("name", sig.name),
+			helpers.String("confidence", detection.Confidence))
+		res = append(res, detection)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return cniConfidenceRank(res[i].Confidence) > cniConfidenceRank(res[j].Confidence)
+	})
+	return res
+}
+
+func cniConfidenceRank(confidence string) int {
+	switch confidence {
+	case CNIConfidenceHigh:
+		return 2
+	case CNIConfidenceMedium:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_findCNIProcesses(t *testing.T) {
+	assert.Equal(t, map[string]int32{
+		"Calico": 200,
+		"Antrea": 201,
+	}, findCNIProcesses("testdata/proc"))
+}
+
+func Test_detectCNIs(t *testing.T) {
+	configs, err := parseCNIConfigDir("testdata/cni/net.d", "/etc/cni/net.d")
+	require.NoError(t, err)
+
+	detections := detectCNIs(context.TODO(), "testdata/proc", []string{"testdata/cni/bin", "testdata/cni/missing"}, configs)
+	assert.Equal(t, []CNIDetection{
+		{
+			Name:       "Calico",
+			Signals:    []string{CNISignalProcess, CNISignalBinary, CNISignalConfig},
+			Confidence: CNIConfidenceHigh,
+			PID:        200,
+		},
+		{
+			Name:       "Antrea",
+			Signals:    []string{CNISignalProcess, CNISignalBinary},
+			Confidence: CNIConfidenceHigh,
+			PID:        201,
+		},
+		{
+			Name:       "Multus",
+			Signals:    []string{CNISignalBinary, CNISignalConfig},
+			Confidence: CNIConfidenceMedium,
+		},
+	}, detections)
+
+	assert.Equal(t, []string{"Calico", "Antrea"}, getCNINames(context.TODO(), detections))
+
+	// only a leftover binary
+	detections = detectCNIs(context.TODO(), "testdata/cni/missing", []string{"testdata/cni/bin"}, nil)
+	require.Len(t, detections, 3)
+	for _, detection := range detections {
+		assert.Equal(t, CNIConfidenceLow, detection.Confidence)
+	}
+	assert.Nil(t, getCNINames(context.TODO(), detections))
+}
+
+func Test_goBinaryVersion(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	_, err = goBinaryVersion(exe)
+	assert.NoError(t, err)
+
+	_, err = goBinaryVersion("testdata/cni/bin/calico")
+	assert.Error(t, err)
+}
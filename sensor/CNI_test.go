@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_getCNINames(t *testing.T) {
@@ -21,10 +22,63 @@ func Test_getCNINames(t *testing.T) {
 	for _, tt := range uid_tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
-			cniList := getCNINames(ctx)
+			cniList := getCNINames(ctx, nil)
 			if !assert.Equal(t, cniList, tt.expected) {
 				t.Logf("%s has different value", tt.name)
 			}
 		})
 	}
 }
+
+func Test_parseCNIConfigDir(t *testing.T) {
+	configs, err := parseCNIConfigDir("testdata/cni/net.d", "/etc/cni/net.d")
+	require.NoError(t, err)
+	require.Len(t, configs, 4)
+
+	multus := configs[0]
+	assert.Equal(t, "/etc/cni/net.d/00-multus.conf", multus.Path)
+	assert.Equal(t, "multus-cni-network", multus.Name)
+	assert.True(t, multus.Active)
+	assert.True(t, multus.MultusDelegated)
+	assert.True(t, multus.NetworkPolicySupport)
+	assert.True(t, multus.Bandwidth)
+	assert.False(t, multus.PortMap)
+	assert.Equal(t, []CNIPlugin{
+		{
+			Type: "multus",
+			Delegates: []CNIPlugin{
+				{Type: "calico"},
+				{Type: "bandwidth", Capabilities: map[string]bool{"bandwidth": true}},
+			},
+		},
+	}, multus.Plugins)
+
+	broken := configs[1]
+	assert.Equal(t, "/etc/cni/net.d/05-broken.conflist", broken.Path)
+	assert.False(t, broken.Active)
+	assert.NotEmpty(t, broken.Err)
+
+	calico := configs[2]
+	assert.Equal(t, CNIConfig{
+		Path:       "/etc/cni/net.d/10-calico.conflist",
+		Name:       "k8s-pod-network",
+		CNIVersion: "0.3.1",
+		Plugins: []CNIPlugin{
+			{Type: "calico"},
+			{Type: "portmap", Capabilities: map[string]bool{"portMappings": true}},
+			{Type: "bandwidth", Capabilities: map[string]bool{"bandwidth": true}},
+		},
+		NetworkPolicySupport: true,
+		Bandwidth:            true,
+		PortMap:              true,
+	}, calico)
+
+	bridge := configs[3]
+	assert.Equal(t, "1.0.0", bridge.CNIVersion)
+	assert.Equal(t, []CNIPlugin{{Type: "bridge"}}, bridge.Plugins)
+	assert.False(t, bridge.Active)
+	assert.False(t, bridge.NetworkPolicySupport)
+
+	_, err = parseCNIConfigDir("testdata/cni/missing", "/etc/cni/missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const loginDefsFileName = "/etc/login.defs"
+
+// Shells which don't allow an interactive login
+var nonLoginShells = []string{"nologin", "false", "sync", "shutdown", "halt"}
+
+// AccountPassword holds the password state of a user, from /etc/shadow. The hash is never reported.
+type AccountPassword struct {
+	// set, empty or locked
+	Status string `json:"status"`
+
+	// The crypt(3) algorithm of the password hash
+	// Example: yescrypt
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+
+	// Days since the epoch of the last password change
+	LastChange *int64 `json:"lastChange,omitempty"`
+	MinDays    *int64 `json:"minDays,omitempty"`
+	MaxDays    *int64 `json:"maxDays,omitempty"`
+	WarnDays   *int64 `json:"warnDays,omitempty"`
+
+	InactiveDays *int64 `json:"inactiveDays,omitempty"`
+
+	// Days since the epoch of the account expiration
+	ExpireDate *int64 `json:"expireDate,omitempty"`
+}
+
+// AccountUser holds a local user
+type AccountUser struct {
+	Username string `json:"username"`
+	UID      int64  `json:"uid"`
+	GID      int64  `json:"gid"`
+	HomeDir  string `json:"homeDir"`
+	Shell    string `json:"shell"`
+
+	// true if the shell allows an interactive login
+	LoginShell bool `json:"loginShell"`
+
+	// The password state (if the user has a shadow entry)
+	Password *AccountPassword `json:"password,omitempty"`
+}
+
+// AccountGroup holds a local group
+type AccountGroup struct {
+	Name    string   `json:"name"`
+	GID     int64    `json:"gid"`
+	Members []string `json:"members"`
+}
+
+// DuplicateID holds a UID or a GID which is shared by several users or groups
+type DuplicateID struct {
+	ID    int64    `json:"id"`
+	Names []string `json:"names"`
+}
+
+// AccountsInfo holds the local users and groups of the host and their audit findings
+type AccountsInfo struct {
+	Users  []AccountUser  `json:"users"`
+	Groups []AccountGroup `json:"groups"`
+
+	// Users other than root with UID 0
+	NonRootUID0 []string `json:"nonRootUID0"`
+
+	DuplicateUIDs []DuplicateID `json:"duplicateUIDs"`
+	DuplicateGIDs []DuplicateID `json:"duplicateGIDs"`
+
+	// Users with a shell which allows an interactive login
+	LoginShellUsers []string `json:"loginShellUsers"`
+
+	// Users with an empty password
+	EmptyPasswordUsers []string `json:"emptyPasswordUsers"`
+
+	// The settings of /etc/login.defs
+	// Example: {"PASS_MAX_DAYS": "365", "ENCRYPT_METHOD": "YESCRYPT"}
+	LoginDefs map[string]string `json:"loginDefs,omitempty"`
+}
+
+// isLoginShell returns true if `shell` allows an interactive login
+func isLoginShell(shell string) bool {
+	if shell == "" {
+		// login(1) falls back to /bin/sh
+		return true
+	}
+	for _, nonLogin := range nonLoginShells {
+		if path.Base(shell) == nonLogin {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLoginDefs parses the `KEY VALUE` settings of /etc/login.defs
+func parseLoginDefs(content []byte) map[string]string {
+	res := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		res[fields[0]] = strings.Trim(strings.Join(fields[1:], " "), `"`)
+	}
+	return res
+}
+
+// findDuplicateIDs returns the IDs of `ids` (name to ID, in order) which are shared by several names
+func findDuplicateIDs(names []string, ids []int64) []DuplicateID {
+	byID := map[int64][]string{}
+	for i, id := range ids {
+		byID[id] = append(byID[id], names[i])
+	}
+	res := make([]DuplicateID, 0)
+	for id, idNames := range byID {
+		if len(idNames) > 1 {
+			res = append(res, DuplicateID{ID: id, Names: idNames})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// senseAccounts reads the users, groups, shadow and login.defs files of the host file system at `rootDir`
+func senseAccounts(ctx context.Context, rootDir string) (*AccountsInfo, error) {
+	ret := AccountsInfo{
+		Users:              make([]AccountUser, 0),
+		Groups:             make([]AccountGroup, 0),
+		NonRootUID0:        make([]string, 0),
+		LoginShellUsers:    make([]string, 0),
+		EmptyPasswordUsers: make([]string, 0),
+	}
+
+	users, err := utils.ReadPasswdFile(rootDir)
+	if err != nil {
+		return &ret, err
+	}
+
+	passwords := map[string]*AccountPassword{}
+	if shadow, err := utils.ReadShadowFile(rootDir); err == nil {
+		for _, entry := range shadow {
+			passwords[entry.Username] = &AccountPassword{
+				Status:        entry.PasswordStatus,
+				HashAlgorithm: entry.HashAlgorithm,
+				LastChange:    entry.LastChange,
+				MinDays:       entry.MinDays,
+				MaxDays:       entry.MaxDays,
+				WarnDays:      entry.WarnDays,
+				InactiveDays:  entry.InactiveDays,
+				ExpireDate:    entry.ExpireDate,
+			}
+		}
+	} else {
+		logger.L().Ctx(ctx).Warning("In SenseAccounts failed to read shadow file", helpers.Error(err))
+	}
+
+	userNames, uids := make([]string, 0, len(users)), make([]int64, 0, len(users))
+	for _, u := range users {
+		user := AccountUser{
+			Username:   u.Username,
+			UID:        u.UID,
+			GID:        u.GID,
+			HomeDir:    u.HomeDir,
+			Shell:      u.Shell,
+			LoginShell: isLoginShell(u.Shell),
+			Password:   passwords[u.Username],
+		}
+		ret.Users = append(ret.Users, user)
+		userNames, uids = append(userNames, u.Username), append(uids, u.UID)
+
+		if u.UID == 0 && u.Username != "root" {
+			ret.NonRootUID0 = append(ret.NonRootUID0, u.Username)
+		}
+		if user.LoginShell {
+			ret.LoginShellUsers = append(ret.LoginShellUsers, u.Username)
+		}
+		if user.Password != nil && user.Password.Status == utils.PasswordStatusEmpty {
+			ret.EmptyPasswordUsers = append(ret.EmptyPasswordUsers, u.Username)
+		}
+	}
+	ret.DuplicateUIDs = findDuplicateIDs(userNames, uids)
+
+	groups, err := utils.ReadGroupFile(rootDir)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseAccounts failed to read group file", helpers.Error(err))
+	}
+	groupNames, gids := make([]string, 0, len(groups)), make([]int64, 0, len(groups))
+	for _, g := range groups {
+		ret.Groups = append(ret.Groups, AccountGroup{Name: g.Name, GID: g.GID, Members: g.Members})
+		groupNames, gids = append(groupNames, g.Name), append(gids, g.GID)
+	}
+	ret.DuplicateGIDs = findDuplicateIDs(groupNames, gids)
+
+	if loginDefs, err := os.ReadFile(path.Join(rootDir, loginDefsFileName)); err == nil {
+		ret.LoginDefs = parseLoginDefs(loginDefs)
+	}
+
+	return &ret, nil
+}
+
+// SenseAccounts returns the local users and groups, the non-root UID 0 accounts, the duplicate UIDs and GIDs,
+// the accounts with login shells, the password state of each user (never the hash) and the login.defs policy
+func SenseAccounts(ctx context.Context) (*AccountsInfo, error) {
+	return senseAccounts(ctx, utils.HostFileSystemDefaultLocation)
+}
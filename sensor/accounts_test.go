@@ -0,0 +1,40 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_senseAccounts(t *testing.T) {
+	info, err := senseAccounts(context.TODO(), "testdata/accounts/root")
+	require.NoError(t, err)
+
+	assert.Len(t, info.Users, 6)
+	assert.Len(t, info.Groups, 6)
+	assert.Equal(t, []string{"toor"}, info.NonRootUID0)
+	assert.Equal(t, []DuplicateID{{ID: 0, Names: []string{"root", "toor"}}, {ID: 1000, Names: []string{"ubuntu", "deploy"}}}, info.DuplicateUIDs)
+	assert.Equal(t, []DuplicateID{{ID: 1001, Names: []string{"deploy", "docker"}}}, info.DuplicateGIDs)
+	assert.Equal(t, []string{"root", "toor", "ubuntu", "deploy"}, info.LoginShellUsers)
+	assert.Equal(t, []string{"toor"}, info.EmptyPasswordUsers)
+	assert.Equal(t, []string{"ubuntu", "deploy"}, info.Groups[2].Members)
+
+	ubuntu := info.Users[4]
+	require.NotNil(t, ubuntu.Password)
+	assert.Equal(t, "set", ubuntu.Password.Status)
+	assert.Equal(t, "sha512", ubuntu.Password.HashAlgorithm)
+	assert.Equal(t, int64(365), *ubuntu.Password.MaxDays)
+	assert.Equal(t, "locked", info.Users[0].Password.Status)
+	assert.Nil(t, info.Users[5].Password)
+
+	assert.Equal(t, "99999", info.LoginDefs["PASS_MAX_DAYS"])
+	assert.Equal(t, "SHA512", info.LoginDefs["ENCRYPT_METHOD"])
+
+	// the password hashes are never reported
+	out, err := json.Marshal(info)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "$6$")
+}
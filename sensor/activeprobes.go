@@ -0,0 +1,156 @@
+package sensor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	// ActiveProbesEnvVar enables the active probes when set to true
+	ActiveProbesEnvVar = "HOST_SCANNER_ACTIVE_PROBES"
+
+	activeProbeTimeout = 2 * time.Second
+
+	// the host address the probes connect to, in the host network namespace
+	activeProbeHost = "127.0.0.1"
+)
+
+// probeTarget is an endpoint which is probed without credentials
+type probeTarget struct {
+	component string
+	method    string
+	url       string
+	body      string
+}
+
+// EndpointProbe holds the result of probing an endpoint without credentials
+type EndpointProbe struct {
+	// Example: kubelet
+	Component string `json:"component"`
+
+	// Example: https://127.0.0.1:10250/pods
+	URL string `json:"url"`
+
+	// true if the endpoint answered with an HTTP response
+	Reachable bool `json:"reachable"`
+
+	// The HTTP status code of the response (if reachable)
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// true if the endpoint served the request without credentials
+	Unauthenticated bool `json:"unauthenticated"`
+
+	// Error connecting the endpoint, if any
+	Err string `json:"err,omitempty"`
+}
+
+// ActiveProbesInfo holds the results of the active probes
+type ActiveProbesInfo struct {
+	Probes []EndpointProbe `json:"probes"`
+}
+
+// defaultProbeTargets returns the endpoints of the node components at `host`
+func defaultProbeTargets(host string) []probeTarget {
+	hostPort := func(port int) string { return net.JoinHostPort(host, strconv.Itoa(port)) }
+	// etcd v3 gRPC gateway, which counts the keys without returning them
+	etcdRange := `{"key":"AA==","range_end":"AA==","count_only":true}`
+	return []probeTarget{
+		{component: "kubelet", method: http.MethodGet, url: "https://" + hostPort(10250) + "/pods"},
+		{component: "kubelet", method: http.MethodGet, url: "http://" + hostPort(10255) + "/pods"},
+		{component: "etcd", method: http.MethodPost, url: "https://" + hostPort(2379) + "/v3/kv/range", body: etcdRange},
+		{component: "etcd", method: http.MethodPost, url: "http://" + hostPort(2379) + "/v3/kv/range", body: etcdRange},
+		{component: "kube-proxy", method: http.MethodGet, url: "http://" + hostPort(10249) + "/metrics"},
+		{component: "kube-proxy", method: http.MethodGet, url: "http://" + hostPort(10256) + "/healthz"},
+	}
+}
+
+// hostNetNsDialContext dials from the host network namespace.
+// The socket is created in the host namespace, and stays there after the thread returns to its namespace.
+func hostNetNsDialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var conn net.Conn
+	err := utils.RunInNetNs(hostNetNsProcPath("ns", "net"), func() error {
+		var err error
+		conn, err = (&net.Dialer{}).DialContext(ctx, network, address)
+		return err
+	})
+	if err != nil && conn != nil {
+		conn.Close()
+	}
+	return conn, err
+}
+
+// newProbeClient returns a shallow copy of the shared http client, which doesn't verify the server certificates.
+// The probes check whether the endpoints authenticate their clients, not the endpoints identity.
+func newProbeClient(dialContext func(ctx context.Context, network, address string) (net.Conn, error)) *http.Client {
+	client := *utils.GetHttpClient()
+	client.Timeout = activeProbeTimeout
+	client.Transport = &http.Transport{
+		DialContext:     dialContext,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	return &client
+}
+
+// probeEndpoints sends requests without credentials to `targets`
+func probeEndpoints(ctx context.Context, client *http.Client, targets []probeTarget) *ActiveProbesInfo {
+	ret := ActiveProbesInfo{Probes: make([]EndpointProbe, 0, len(targets))}
+	for _, target := range targets {
+		probe := EndpointProbe{Component: target.component, URL: target.url}
+
+		req, err := http.NewRequestWithContext(ctx, target.method, target.url, strings.NewReader(target.body))
+		if err != nil {
+			probe.Err = err.Error()
+			ret.Probes = append(ret.Probes, probe)
+			continue
+		}
+		if target.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			probe.Err = err.Error()
+			ret.Probes = append(ret.Probes, probe)
+			continue
+		}
+		io.Copy(io.Discard, io.LimitReader(res.Body, 1<<20))
+		res.Body.Close()
+
+		probe.Reachable = true
+		probe.StatusCode = res.StatusCode
+		probe.Unauthenticated = res.StatusCode >= 200 && res.StatusCode < 300
+		if probe.Unauthenticated {
+			logger.L().Ctx(ctx).Debug("endpoint answered without credentials",
+				helpers.String("component", target.component), helpers.String("url", target.url))
+		}
+		ret.Probes = append(ret.Probes, probe)
+	}
+	return &ret
+}
+
+// SenseActiveProbes connects the kubelet, etcd and kube-proxy endpoints of the node without credentials,
+// and reports which of them answer unauthenticated.
+// The sensor is opt-in: it runs only if the `HOST_SCANNER_ACTIVE_PROBES` environment variable is true.
+func SenseActiveProbes(ctx context.Context) (*ActiveProbesInfo, error) {
+	if enabled, _ := strconv.ParseBool(os.Getenv(ActiveProbesEnvVar)); !enabled {
+		return nil, &SenseError{
+			Massage:  fmt.Sprintf("active probes are disabled, set %s=true to enable them", ActiveProbesEnvVar),
+			Function: "SenseActiveProbes",
+			Code:     http.StatusForbidden,
+		}
+	}
+
+	return probeEndpoints(ctx, newProbeClient(hostNetNsDialContext), defaultProbeTargets(activeProbeHost)), nil
+}
@@ -0,0 +1,85 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_probeEndpoints(t *testing.T) {
+	// kubelet with anonymous authentication disabled
+	securedKubelet := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"kind":"PodList"}`))
+	}))
+	defer securedKubelet.Close()
+
+	// kubelet read-only port
+	readOnlyKubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pods", r.URL.Path)
+		w.Write([]byte(`{"kind":"PodList"}`))
+	}))
+	defer readOnlyKubelet.Close()
+
+	// etcd without client certificate authentication
+	etcd := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || !strings.Contains(string(body), "count_only") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"count":"42"}`))
+	}))
+	defer etcd.Close()
+
+	// a closed port
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	targets := []probeTarget{
+		{component: "kubelet", method: http.MethodGet, url: securedKubelet.URL + "/pods"},
+		{component: "kubelet", method: http.MethodGet, url: readOnlyKubelet.URL + "/pods"},
+		{component: "etcd", method: http.MethodPost, url: etcd.URL + "/v3/kv/range", body: `{"key":"AA==","count_only":true}`},
+		{component: "kube-proxy", method: http.MethodGet, url: closedURL + "/healthz"},
+	}
+	res := probeEndpoints(context.TODO(), newProbeClient(nil), targets)
+	require.Len(t, res.Probes, 4)
+
+	assert.Equal(t, EndpointProbe{Component: "kubelet", URL: targets[0].url, Reachable: true, StatusCode: http.StatusUnauthorized}, res.Probes[0])
+	assert.Equal(t, EndpointProbe{Component: "kubelet", URL: targets[1].url, Reachable: true, StatusCode: http.StatusOK, Unauthenticated: true}, res.Probes[1])
+	assert.Equal(t, EndpointProbe{Component: "etcd", URL: targets[2].url, Reachable: true, StatusCode: http.StatusOK, Unauthenticated: true}, res.Probes[2])
+	assert.False(t, res.Probes[3].Reachable)
+	assert.False(t, res.Probes[3].Unauthenticated)
+	assert.NotEmpty(t, res.Probes[3].Err)
+}
+
+func Test_defaultProbeTargets(t *testing.T) {
+	targets := defaultProbeTargets("127.0.0.1")
+	urls := make([]string, 0, len(targets))
+	for _, target := range targets {
+		urls = append(urls, target.url)
+	}
+	assert.Contains(t, urls, "https://127.0.0.1:10250/pods")
+	assert.Contains(t, urls, "http://127.0.0.1:10255/pods")
+	assert.Contains(t, urls, "https://127.0.0.1:2379/v3/kv/range")
+	assert.Contains(t, urls, "http://127.0.0.1:10256/healthz")
+}
+
+func TestSenseActiveProbesDisabled(t *testing.T) {
+	t.Setenv(ActiveProbesEnvVar, "")
+	_, err := SenseActiveProbes(context.TODO())
+	var senseErr *SenseError
+	require.True(t, errors.As(err, &senseErr))
+	assert.Equal(t, http.StatusForbidden, senseErr.Code)
+}
@@ -0,0 +1,114 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	appArmorProfilesFileName = "/sys/kernel/security/apparmor/profiles"
+
+	appArmorEnforceMode    = "enforce"
+	appArmorUnconfinedMode = "unconfined"
+)
+
+// The default AppArmor profiles of containerd and CRI-O
+var appArmorRuntimeProfiles = []string{"cri-containerd.apparmor.d", "crio-default"}
+
+// parseAppArmorLabel splits an AppArmor label, e.g. `/usr/bin/man (enforce)`, into the profile name and mode.
+// The unconfined label has no mode.
+func parseAppArmorLabel(label string) (name, mode string) {
+	label = strings.TrimSpace(label)
+	if label == appArmorUnconfinedMode {
+		return label, appArmorUnconfinedMode
+	}
+	if idx := strings.LastIndex(label, " ("); idx != -1 && strings.HasSuffix(label, ")") {
+		return label[:idx], label[idx+2 : len(label)-1]
+	}
+	return label, ""
+}
+
+// parseAppArmorProfiles parses the content of /sys/kernel/security/apparmor/profiles
+func parseAppArmorProfiles(content []byte) []ds.AppArmorProfile {
+	res := make([]ds.AppArmorProfile, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		name, mode := parseAppArmorLabel(scanner.Text())
+		res = append(res, ds.AppArmorProfile{Name: name, Mode: mode})
+	}
+	return res
+}
+
+// appArmorConfinedProcesses returns the processes under `procDir` which are confined by an AppArmor profile
+func appArmorConfinedProcesses(procDir string) []ds.ProcessSecurityLabel {
+	res := make([]ds.ProcessSecurityLabel, 0)
+	pidDirs, err := os.ReadDir(procDir)
+	if err != nil {
+		return res
+	}
+	for _, pidDir := range pidDirs {
+		// since processes are about to die in the middle of the loop, we will ignore next errors
+		pid, err := strconv.ParseInt(pidDir.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		label, err := processSecurityLabel(procDir, int32(pid), "apparmor")
+		if err != nil || label == "" || label == appArmorUnconfinedMode {
+			continue
+		}
+		name, err := processComm(procDir, int32(pid))
+		if err != nil {
+			continue
+		}
+		res = append(res, ds.ProcessSecurityLabel{Name: name, PID: int32(pid), Label: label})
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].PID < res[j].PID })
+	return res
+}
+
+// senseAppArmor parses the loaded AppArmor profiles `content`, and reads the process labels under `procDir`
+func senseAppArmor(content []byte, procDir string) *ds.AppArmorStatus {
+	res := ds.AppArmorStatus{
+		Profiles:          parseAppArmorProfiles(content),
+		ModeCounts:        map[string]int{},
+		RuntimeProfiles:   make([]ds.AppArmorRuntimeProfile, 0, len(appArmorRuntimeProfiles)),
+		ConfinedProcesses: appArmorConfinedProcesses(procDir),
+	}
+	for _, profile := range res.Profiles {
+		res.ModeCounts[profile.Mode]++
+	}
+	for _, name := range appArmorRuntimeProfiles {
+		runtimeProfile := ds.AppArmorRuntimeProfile{Name: name}
+		for _, profile := range res.Profiles {
+			if profile.Name == name {
+				runtimeProfile.Loaded = true
+				runtimeProfile.Enforcing = profile.Mode == appArmorEnforceMode
+				break
+			}
+		}
+		res.RuntimeProfiles = append(res.RuntimeProfiles, runtimeProfile)
+	}
+	return &res
+}
+
+// getAppArmorStatus returns the raw AppArmor profiles, "unloaded" or "stopped", and the structured AppArmor status if loaded
+func getAppArmorStatus() (string, *ds.AppArmorStatus) {
+	if _, err := os.Stat(utils.HostPath(appArmorProfilesFileName)); err != nil {
+		return "unloaded", nil
+	}
+	content, err := utils.ReadFileOnHostFileSystem(appArmorProfilesFileName)
+	if err != nil || len(content) == 0 {
+		return "stopped", nil
+	}
+	return string(content), senseAppArmor(content, procDirName)
+}
@@ -0,0 +1,39 @@
+package sensor
+
+import (
+	"testing"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseAppArmorLabel(t *testing.T) {
+	name, mode := parseAppArmorLabel("/usr/bin/man (enforce)")
+	assert.Equal(t, "/usr/bin/man", name)
+	assert.Equal(t, "enforce", mode)
+
+	name, mode = parseAppArmorLabel("docker-default (enforce) (kill)")
+	assert.Equal(t, "docker-default (enforce)", name)
+	assert.Equal(t, "kill", mode)
+
+	name, mode = parseAppArmorLabel("unconfined")
+	assert.Equal(t, "unconfined", name)
+	assert.Equal(t, "unconfined", mode)
+}
+
+func Test_senseAppArmor(t *testing.T) {
+	profiles := []byte("/usr/sbin/cups-browsed (complain)\n/usr/bin/man (enforce)\ncri-containerd.apparmor.d (enforce)\nlsb_release (enforce)\n")
+	status := senseAppArmor(profiles, "testdata/apparmor/proc")
+
+	assert.Len(t, status.Profiles, 4)
+	assert.Equal(t, map[string]int{"enforce": 3, "complain": 1}, status.ModeCounts)
+	assert.Equal(t, []ds.AppArmorRuntimeProfile{
+		{Name: "cri-containerd.apparmor.d", Loaded: true, Enforcing: true},
+		{Name: "crio-default"},
+	}, status.RuntimeProfiles)
+	assert.Equal(t, []ds.ProcessSecurityLabel{
+		// the name is not taken from the cmdline, which nginx rewrites
+		{Name: "nginx", PID: 401, Label: "cri-containerd.apparmor.d (enforce)"},
+		{Name: "cups-browsed", PID: 402, Label: "/usr/sbin/cups-browsed (complain)"},
+	}, status.ConfinedProcesses)
+}
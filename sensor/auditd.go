@@ -0,0 +1,259 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	auditdConfFileName  = "/etc/audit/auditd.conf"
+	auditRulesFileName  = "/etc/audit/audit.rules"
+	auditRulesDir       = "/etc/audit/rules.d"
+	auditdProcessSuffix = "/auditd"
+)
+
+// The files and dirs which should be watched by audit rules. Only those which exist on the host are required.
+var requiredAuditWatches = []string{
+	"/etc/kubernetes",
+	"/var/lib/kubelet",
+	"/usr/bin/containerd",
+	"/usr/bin/containerd-shim-runc-v2",
+	"/usr/bin/runc",
+	"/usr/sbin/runc",
+	"/usr/bin/dockerd",
+	"/usr/bin/crio",
+	"/etc/containerd",
+	"/etc/docker",
+	"/etc/passwd",
+	"/etc/group",
+	"/etc/shadow",
+	"/etc/gshadow",
+	"/etc/security/opasswd",
+	"/etc/sudoers",
+	"/etc/sudoers.d",
+}
+
+// AuditWatch holds a file watch rule, e.g. `-w /etc/passwd -p wa -k identity`
+type AuditWatch struct {
+	Path string `json:"path"`
+
+	// r, w, x and a (attribute change)
+	Permissions string `json:"permissions,omitempty"`
+	Key         string `json:"key,omitempty"`
+
+	// The rules file of the rule
+	File string `json:"file"`
+}
+
+// AuditSyscallRule holds a syscall rule, e.g. `-a always,exit -F arch=b64 -S setuid -k privileged`
+type AuditSyscallRule struct {
+	// always or never
+	Action string `json:"action"`
+
+	// exit, task, user, exclude or filesystem
+	List string `json:"list"`
+
+	Syscalls []string `json:"syscalls,omitempty"`
+
+	// Example: ["arch=b64", "auid>=1000"]
+	Fields []string `json:"fields,omitempty"`
+	Key    string   `json:"key,omitempty"`
+	File   string   `json:"file"`
+}
+
+// AuditInfo holds the state of the Linux audit framework
+type AuditInfo struct {
+	// true if auditd is running
+	Running bool  `json:"running"`
+	PID     int32 `json:"pid,omitempty"`
+
+	// The settings of /etc/audit/auditd.conf
+	// Example: {"max_log_file_action": "keep_logs", "space_left_action": "email"}
+	Config map[string]string `json:"config,omitempty"`
+
+	// The rules files, in the order they are loaded
+	RulesFiles []string `json:"rulesFiles"`
+
+	// The control rules, e.g. `-e 2`
+	Controls     []string           `json:"controls"`
+	Watches      []AuditWatch       `json:"watches"`
+	SyscallRules []AuditSyscallRule `json:"syscallRules"`
+
+	// Required watches of existing files which no rule covers
+	MissingWatches []string `json:"missingWatches"`
+}
+
+// parseKeyValueConf parses a `key = value` config file, e.g. auditd.conf
+func parseKeyValueConf(content []byte) map[string]string {
+	res := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok {
+			res[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return res
+}
+
+// parseAuditRules parses the rules of an audit rules file into `info`
+func parseAuditRules(content []byte, filePath string, info *AuditInfo) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		// the value of the option at index `i`, or empty if missing
+		value := func(i int) string {
+			if i+1 < len(fields) {
+				return fields[i+1]
+			}
+			return ""
+		}
+
+		switch fields[0] {
+		case "-w":
+			watch := AuditWatch{Path: value(0), File: filePath}
+			for i := 2; i < len(fields); i++ {
+				switch fields[i] {
+				case "-p":
+					watch.Permissions = value(i)
+					i++
+				case "-k":
+					watch.Key = value(i)
+					i++
+				}
+			}
+			info.Watches = append(info.Watches, watch)
+		case "-a", "-A":
+			// both `action,list` and `list,action` are accepted
+			rule := AuditSyscallRule{File: filePath}
+			for _, part := range strings.Split(value(0), ",") {
+				if part == "always" || part == "never" {
+					rule.Action = part
+				} else {
+					rule.List = part
+				}
+			}
+			for i := 2; i < len(fields); i++ {
+				switch fields[i] {
+				case "-S":
+					rule.Syscalls = append(rule.Syscalls, strings.Split(value(i), ",")...)
+					i++
+				case "-F":
+					field := value(i)
+					if key, ok := strings.CutPrefix(field, "key="); ok {
+						rule.Key = key
+					} else {
+						rule.Fields = append(rule.Fields, field)
+					}
+					i++
+				case "-k":
+					rule.Key = value(i)
+					i++
+				}
+			}
+			info.SyscallRules = append(info.SyscallRules, rule)
+		default:
+			if strings.HasPrefix(fields[0], "-") {
+				info.Controls = append(info.Controls, strings.Join(fields, " "))
+			}
+		}
+	}
+}
+
+// auditWatchedPaths returns the paths which are watched by `-w` rules, or by `-F path=` and `-F dir=` fields
+func auditWatchedPaths(info *AuditInfo) []string {
+	res := []string{}
+	for _, watch := range info.Watches {
+		res = append(res, path.Clean(watch.Path))
+	}
+	for _, rule := range info.SyscallRules {
+		for _, field := range rule.Fields {
+			if watched, ok := strings.CutPrefix(field, "path="); ok {
+				res = append(res, path.Clean(watched))
+			} else if watched, ok := strings.CutPrefix(field, "dir="); ok {
+				res = append(res, path.Clean(watched))
+			}
+		}
+	}
+	return res
+}
+
+// isAuditWatched returns true if `filePath` or one of its parent dirs is watched
+func isAuditWatched(filePath string, watched []string) bool {
+	for _, w := range watched {
+		if w == filePath || strings.HasPrefix(filePath, strings.TrimSuffix(w, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// senseAudit reads the audit config and rules of the host file system at `rootDir`, and looks for auditd under `procDir`
+func senseAudit(ctx context.Context, rootDir, procDir string) (*AuditInfo, error) {
+	ret := AuditInfo{
+		RulesFiles:     make([]string, 0),
+		Controls:       make([]string, 0),
+		Watches:        make([]AuditWatch, 0),
+		SyscallRules:   make([]AuditSyscallRule, 0),
+		MissingWatches: make([]string, 0),
+	}
+
+	if pid, ok := locateProcessesBySuffix(procDir, []string{auditdProcessSuffix})[auditdProcessSuffix]; ok {
+		ret.Running = true
+		ret.PID = pid
+	}
+
+	if content, err := os.ReadFile(path.Join(rootDir, auditdConfFileName)); err == nil {
+		ret.Config = parseKeyValueConf(content)
+	} else {
+		logger.L().Ctx(ctx).Debug("In SenseAudit failed to read auditd.conf", helpers.Error(err))
+	}
+
+	// augenrules compiles rules.d into audit.rules, so rules.d is the source when it exists
+	rulesFiles := overlayConfFiles(rootDir, []string{auditRulesDir}, ".rules")
+	if len(rulesFiles) == 0 {
+		rulesFiles = []string{auditRulesFileName}
+	}
+	for _, rulesFile := range rulesFiles {
+		content, err := os.ReadFile(path.Join(rootDir, rulesFile))
+		if err != nil {
+			logger.L().Ctx(ctx).Debug("In SenseAudit failed to read rules file", helpers.String("path", rulesFile), helpers.Error(err))
+			continue
+		}
+		ret.RulesFiles = append(ret.RulesFiles, rulesFile)
+		parseAuditRules(content, rulesFile, &ret)
+	}
+
+	watched := auditWatchedPaths(&ret)
+	for _, required := range requiredAuditWatches {
+		if _, err := os.Stat(path.Join(rootDir, required)); err != nil {
+			continue
+		}
+		if !isAuditWatched(required, watched) {
+			ret.MissingWatches = append(ret.MissingWatches, required)
+		}
+	}
+
+	return &ret, nil
+}
+
+// SenseAudit returns whether auditd is running, its config, the audit watches and syscall rules with their keys,
+// and the required watches which are missing
+func SenseAudit(ctx context.Context) (*AuditInfo, error) {
+	return senseAudit(ctx, utils.HostFileSystemDefaultLocation, procDirName)
+}
@@ -0,0 +1,48 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_senseAudit(t *testing.T) {
+	info, err := senseAudit(context.TODO(), "testdata/audit/root", "testdata/audit/proc")
+	require.NoError(t, err)
+
+	assert.True(t, info.Running)
+	assert.Equal(t, int32(800), info.PID)
+	assert.Equal(t, "ROTATE", info.Config["max_log_file_action"])
+
+	// rules.d takes precedence over audit.rules
+	assert.Equal(t, []string{
+		"/etc/audit/rules.d/10-base-config.rules",
+		"/etc/audit/rules.d/30-identity.rules",
+		"/etc/audit/rules.d/40-kubernetes.rules",
+		"/etc/audit/rules.d/99-finalize.rules",
+	}, info.RulesFiles)
+	assert.Equal(t, []string{"-D", "-b 8192", "--backlog_wait_time 60000", "-f 1", "-e 2"}, info.Controls)
+
+	require.Len(t, info.Watches, 4)
+	assert.Equal(t, AuditWatch{Path: "/etc/passwd", Permissions: "wa", Key: "identity", File: "/etc/audit/rules.d/30-identity.rules"}, info.Watches[0])
+
+	assert.Equal(t, []AuditSyscallRule{
+		{Action: "always", List: "exit", Fields: []string{"path=/usr/bin/containerd", "perm=x", "auid>=1000", "auid!=unset"},
+			Key: "containerd", File: "/etc/audit/rules.d/40-kubernetes.rules"},
+		{Action: "always", List: "exit", Syscalls: []string{"setuid", "setgid", "setreuid"}, Fields: []string{"arch=b64"},
+			Key: "privileged", File: "/etc/audit/rules.d/40-kubernetes.rules"},
+	}, info.SyscallRules)
+
+	// /etc/kubernetes/ covers its files, and the missing identity files are not required
+	assert.Equal(t, []string{"/var/lib/kubelet", "/usr/bin/runc", "/etc/gshadow"}, info.MissingWatches)
+}
+
+func Test_senseAuditNoRulesD(t *testing.T) {
+	info, err := senseAudit(context.TODO(), "testdata/audit/missing", "testdata/audit/missing")
+	require.NoError(t, err)
+	assert.False(t, info.Running)
+	assert.Empty(t, info.RulesFiles)
+	assert.Empty(t, info.MissingWatches)
+}
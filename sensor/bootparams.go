@@ -0,0 +1,189 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	cpuVulnerabilitiesDir = "/sys/devices/system/cpu/vulnerabilities"
+
+	// parsed statuses of the CPU vulnerabilities
+	CPUVulnerabilityNotAffected = "Not affected"
+	CPUVulnerabilityMitigation  = "Mitigation"
+	CPUVulnerabilityVulnerable  = "Vulnerable"
+	CPUVulnerabilityUnknown     = "Unknown"
+)
+
+// Boot parameters which disable CPU vulnerability mitigations: a parameter, or `parameter=value`
+var mitigationDisablingBootParams = []string{
+	"mitigations=off",
+	"nopti",
+	"pti=off",
+	"nospectre_v1",
+	"nospectre_v2",
+	"spectre_v2=off",
+	"spectre_v2_user=off",
+	"nospec_store_bypass_disable",
+	"spec_store_bypass_disable=off",
+	"l1tf=off",
+	"mds=off",
+	"tsx_async_abort=off",
+	"mmio_stale_data=off",
+	"retbleed=off",
+	"srbds=off",
+	"gather_data_sampling=off",
+	"spectre_bhi=off",
+	"reg_file_data_sampling=off",
+}
+
+// BootParameter holds a parameter of the kernel command line
+type BootParameter struct {
+	// Example: systemd.unified_cgroup_hierarchy
+	Key string `json:"key"`
+
+	// Empty for parameters without a value, e.g. `nopti`
+	Value string `json:"value,omitempty"`
+}
+
+// CPUVulnerability holds an entry of /sys/devices/system/cpu/vulnerabilities
+type CPUVulnerability struct {
+	// Example: spectre_v2
+	Name string `json:"name"`
+
+	// Not affected, Mitigation, Vulnerable or Unknown
+	Status string `json:"status"`
+
+	// The raw content of the entry
+	// Example: Mitigation: Enhanced / Automatic IBRS; IBPB: conditional; RSB filling
+	Details string `json:"details"`
+}
+
+// BootParamsInfo holds the kernel command line and the CPU vulnerabilities state
+type BootParamsInfo struct {
+	CmdLine    string          `json:"cmdLine"`
+	Parameters []BootParameter `json:"parameters"`
+
+	// The parameters which disable CPU vulnerability mitigations
+	// Example: ["mitigations=off"]
+	DisabledMitigations []string `json:"disabledMitigations"`
+
+	Vulnerabilities []CPUVulnerability `json:"vulnerabilities"`
+}
+
+// parseKernelCmdLine splits a kernel command line into parameters. Double quotes may wrap spaces in values,
+// e.g. `dyndbg="file x.c +p"`.
+func parseKernelCmdLine(cmdLine string) []BootParameter {
+	res := make([]BootParameter, 0)
+	var fields []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range strings.TrimSpace(cmdLine) {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	for _, field := range fields {
+		// parameters after `--` are passed to init
+		if field == "--" {
+			break
+		}
+		key, value, _ := strings.Cut(field, "=")
+		res = append(res, BootParameter{Key: key, Value: value})
+	}
+	return res
+}
+
+// disabledMitigations returns the parameters which disable CPU vulnerability mitigations
+func disabledMitigations(params []BootParameter) []string {
+	res := make([]string, 0)
+	for _, param := range params {
+		for _, disabling := range mitigationDisablingBootParams {
+			key, value, hasValue := strings.Cut(disabling, "=")
+			if param.Key == key && (!hasValue || param.Value == value) {
+				res = append(res, disabling)
+			}
+		}
+	}
+	return res
+}
+
+// parseCPUVulnerability parses the content of a /sys/devices/system/cpu/vulnerabilities entry.
+// The status may follow the `<word>: ` prefixes of a subsystem, e.g. `KVM: Mitigation: VMX disabled`.
+func parseCPUVulnerability(name, content string) CPUVulnerability {
+	res := CPUVulnerability{Name: name, Status: CPUVulnerabilityUnknown, Details: strings.TrimSpace(content)}
+	details := res.Details
+	for {
+		for _, status := range []string{CPUVulnerabilityNotAffected, CPUVulnerabilityMitigation, CPUVulnerabilityVulnerable} {
+			if strings.HasPrefix(details, status) {
+				res.Status = status
+				return res
+			}
+		}
+		prefix, rest, found := strings.Cut(details, ": ")
+		if !found || prefix == "" || strings.ContainsAny(prefix, " \t") {
+			return res
+		}
+		details = rest
+	}
+}
+
+// senseBootParams reads the kernel command line under `procDir`, and the CPU vulnerabilities of the host
+// file system at `rootDir`
+func senseBootParams(ctx context.Context, rootDir, procDir string) (*BootParamsInfo, error) {
+	ret := BootParamsInfo{
+		Parameters:          make([]BootParameter, 0),
+		DisabledMitigations: make([]string, 0),
+		Vulnerabilities:     make([]CPUVulnerability, 0),
+	}
+
+	cmdLine, err := os.ReadFile(path.Join(procDir, "cmdline"))
+	if err != nil {
+		return &ret, err
+	}
+	ret.CmdLine = strings.TrimSpace(string(cmdLine))
+	ret.Parameters = parseKernelCmdLine(ret.CmdLine)
+	ret.DisabledMitigations = disabledMitigations(ret.Parameters)
+
+	vulnDir := path.Join(rootDir, cpuVulnerabilitiesDir)
+	entries, err := os.ReadDir(vulnDir)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseBootParams failed to read CPU vulnerabilities", helpers.Error(err))
+		return &ret, nil
+	}
+	for _, entry := range entries {
+		content, err := os.ReadFile(path.Join(vulnDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		ret.Vulnerabilities = append(ret.Vulnerabilities, parseCPUVulnerability(entry.Name(), string(content)))
+	}
+	sort.SliceStable(ret.Vulnerabilities, func(i, j int) bool { return ret.Vulnerabilities[i].Name < ret.Vulnerabilities[j].Name })
+
+	return &ret, nil
+}
+
+// SenseBootParams returns the kernel command line parameters, the ones which disable CPU vulnerability
+// mitigations, and the mitigation status of each CPU vulnerability
+func SenseBootParams(ctx context.Context) (*BootParamsInfo, error) {
+	return senseBootParams(ctx, utils.HostFileSystemDefaultLocation, procDirName)
+}
@@ -0,0 +1,55 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_senseBootParams(t *testing.T) {
+	info, err := senseBootParams(context.TODO(), "testdata/bootparams/root", "testdata/bootparams/proc")
+	require.NoError(t, err)
+
+	assert.Equal(t, []BootParameter{
+		{Key: "BOOT_IMAGE", Value: "/boot/vmlinuz-6.8.0-45-generic"},
+		{Key: "root", Value: "UUID=3f1c"},
+		{Key: "ro"},
+		{Key: "audit", Value: "1"},
+		{Key: "apparmor", Value: "1"},
+		{Key: "security", Value: "apparmor"},
+		{Key: "mitigations", Value: "off"},
+		{Key: "nopti"},
+		{Key: "lockdown", Value: "integrity"},
+		{Key: "init_on_alloc", Value: "1"},
+		{Key: "systemd.unified_cgroup_hierarchy", Value: "1"},
+		{Key: "dyndbg", Value: "file drivers/net/* +p"},
+		{Key: "quiet"},
+	}, info.Parameters)
+	assert.Equal(t, []string{"mitigations=off", "nopti"}, info.DisabledMitigations)
+
+	assert.Equal(t, []CPUVulnerability{
+		{Name: "itlb_multihit", Status: CPUVulnerabilityUnknown, Details: "Unknown: Dependent on hypervisor status"},
+		{Name: "meltdown", Status: CPUVulnerabilityNotAffected, Details: "Not affected"},
+		{Name: "spectre_v1", Status: CPUVulnerabilityVulnerable, Details: "Vulnerable: __user pointer sanitization and usercopy barriers only; no swapgs barriers"},
+		{Name: "spectre_v2", Status: CPUVulnerabilityMitigation, Details: "Mitigation: Enhanced / Automatic IBRS; IBPB: conditional; RSB filling; PBRSB-eIBRS: SW sequence; BHI: BHI_DIS_S"},
+	}, info.Vulnerabilities)
+}
+
+func Test_parseCPUVulnerability(t *testing.T) {
+	tests := map[string]string{
+		"Not affected":    CPUVulnerabilityNotAffected,
+		"Mitigation: PTI": CPUVulnerabilityMitigation,
+		"Vulnerable: Clear CPU buffers attempted, no microcode; SMT vulnerable": CPUVulnerabilityVulnerable,
+		"KVM: Mitigation: VMX disabled":                                         CPUVulnerabilityMitigation,
+		"KVM: Vulnerable":                                                       CPUVulnerabilityVulnerable,
+		"Unknown: Dependent on hypervisor status":                               CPUVulnerabilityUnknown,
+		"Processor vulnerable":                                                  CPUVulnerabilityUnknown,
+	}
+	for content, status := range tests {
+		vuln := parseCPUVulnerability("itlb_multihit", content+"\n")
+		assert.Equal(t, status, vuln.Status, content)
+		assert.Equal(t, content, vuln.Details)
+	}
+}
@@ -0,0 +1,157 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/weaveworks/procspy"
+)
+
+// componentEndpoint describes a well known endpoint of a Kubernetes component
+type componentEndpoint struct {
+	component   string
+	description string
+
+	// the listening port, 0 for any port of `processName`
+	port uint16
+
+	// the name of the process, used to match endpoints without a well known port
+	processName string
+
+	// the flags configuring the endpoint (the first one which is set is reported)
+	flags []string
+}
+
+var componentEndpoints = []componentEndpoint{
+	{component: "kubelet", description: "kubelet API", port: 10250, flags: []string{"--address"}},
+	{component: "kubelet", description: "kubelet read-only API", port: 10255, flags: []string{"--read-only-port"}},
+	{component: "kubelet", description: "kubelet healthz", port: 10248, flags: []string{"--healthz-bind-address"}},
+	{component: "kube-proxy", description: "kube-proxy metrics", port: 10249, flags: []string{kubeProxyMetricsBindAddressArgName}},
+	{component: "kube-proxy", description: "kube-proxy healthz", port: 10256, flags: []string{kubeProxyHealthzBindAddressArgName}},
+	{component: "etcd", description: "etcd client API", port: 2379, flags: []string{"--listen-client-urls"}},
+	{component: "etcd", description: "etcd peer API", port: 2380, flags: []string{"--listen-peer-urls"}},
+	{component: "etcd", description: "etcd metrics", port: 2381, flags: []string{"--listen-metrics-urls"}},
+	{component: "kube-scheduler", description: "kube-scheduler secure port", port: 10259, flags: []string{"--bind-address"}},
+	{component: "kube-controller-manager", description: "kube-controller-manager secure port", port: 10257, flags: []string{"--bind-address"}},
+	{component: "docker", description: "Docker API without TLS", port: 2375, flags: []string{"--host", "-H"}},
+	{component: "docker", description: "Docker API with TLS", port: 2376, flags: []string{"--host", "-H"}},
+	{component: "containerd", description: "containerd metrics", port: 1338},
+	// containerd listens on TCP only for metrics and CRI streaming, both configured in its config file
+	{component: "containerd", description: "containerd metrics or CRI streaming", processName: "containerd"},
+}
+
+// ComponentEndpoint holds a listening endpoint of a Kubernetes component
+type ComponentEndpoint struct {
+	// Example: kubelet
+	Component string `json:"component"`
+
+	// Example: kubelet read-only API
+	Description string `json:"description"`
+
+	Transport string `json:"transport"`
+	Port      uint16 `json:"port"`
+
+	// The address the endpoint is bound to
+	// Example: 0.0.0.0
+	Address string `json:"address"`
+
+	// true if the endpoint is bound to a non-loopback address
+	Exposed bool `json:"exposed"`
+
+	// The process owning the listening socket (if found)
+	Process *procspy.Proc `json:"process,omitempty"`
+
+	// The flag configuring the endpoint and its value on the owning process command line (if set)
+	// Example: --read-only-port=10255
+	Flag string `json:"flag,omitempty"`
+}
+
+// ComponentEndpointsInfo holds the listening endpoints of the Kubernetes components on the host
+type ComponentEndpointsInfo struct {
+	Endpoints []ComponentEndpoint `json:"endpoints"`
+}
+
+// matchComponentEndpoint returns the known endpoint which matches the listening `port` of process `procName`
+func matchComponentEndpoint(port uint16, procName string) *componentEndpoint {
+	for i := range componentEndpoints {
+		if componentEndpoints[i].port == port {
+			return &componentEndpoints[i]
+		}
+	}
+	for i := range componentEndpoints {
+		if componentEndpoints[i].port == 0 && procName != "" && componentEndpoints[i].processName == procName {
+			return &componentEndpoints[i]
+		}
+	}
+	return nil
+}
+
+// componentEndpointFlag returns `flag=value` of the first flag of the endpoint which is set on process `pid`
+func componentEndpointFlag(procDir string, pid uint, endpoint *componentEndpoint) string {
+	if len(endpoint.flags) == 0 {
+		return ""
+	}
+	proc, err := processCmdLine(procDir, pid)
+	if err != nil {
+		return ""
+	}
+	for _, flag := range endpoint.flags {
+		if val, ok := proc.GetArg(flag); ok {
+			return fmt.Sprintf("%s=%s", flag, val)
+		}
+	}
+	return ""
+}
+
+// senseComponentEndpoints matches the listening TCP sockets of the network namespace of process `pid`
+// with the known endpoints of the Kubernetes components
+func senseComponentEndpoints(ctx context.Context, procDir string, pid int32) (*ComponentEndpointsInfo, error) {
+	ret := ComponentEndpointsInfo{Endpoints: make([]ComponentEndpoint, 0)}
+
+	sockets, err := readProcNetSockets(ctx, procNetPaths(procDir, pid, ProcNetTCPPaths), "tcp")
+	if err != nil {
+		return &ret, err
+	}
+
+	owners, err := socketOwners(procDir)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseComponentEndpoints failed to find sockets owners", helpers.Error(err))
+	}
+
+	for _, socket := range sockets {
+		if socket.State != tcpListeningState {
+			continue
+		}
+		owner, hasOwner := owners[socket.Inode]
+		endpoint := matchComponentEndpoint(socket.LocalPort, owner.Name)
+		if endpoint == nil {
+			continue
+		}
+		res := ComponentEndpoint{
+			Component:   endpoint.component,
+			Description: endpoint.description,
+			Transport:   socket.Transport,
+			Port:        socket.LocalPort,
+			Address:     socket.LocalAddress.String(),
+			Exposed:     !socket.LocalAddress.IsLoopback(),
+		}
+		if hasOwner {
+			res.Process = &owner
+			res.Flag = componentEndpointFlag(procDir, owner.PID, endpoint)
+		}
+		ret.Endpoints = append(ret.Endpoints, res)
+	}
+
+	sort.SliceStable(ret.Endpoints, func(i, j int) bool { return ret.Endpoints[i].Port < ret.Endpoints[j].Port })
+
+	return &ret, nil
+}
+
+// SenseComponentEndpoints returns the listening endpoints of the Kubernetes components on the host,
+// and whether they are exposed beyond localhost
+func SenseComponentEndpoints(ctx context.Context) (*ComponentEndpointsInfo, error) {
+	return senseComponentEndpoints(ctx, procDirName, hostNetNsPID)
+}
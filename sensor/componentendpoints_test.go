@@ -0,0 +1,56 @@
+package sensor
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/procspy"
+)
+
+func Test_parseProcNetSockets(t *testing.T) {
+	content := []byte(`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:2808 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 31003 1 0000000000000000 100 0 0 10 0
+   1: 0500000A:280A 0600000A:D431 01 00000000:00000000 00:00000000 00000000     0        0 31008 1 0000000000000000 20 4 30 10 -1
+   2: invalid
+`)
+	sockets := parseProcNetSockets(content, "tcp")
+	require.Len(t, sockets, 2)
+	assert.Equal(t, procNetSocket{
+		Transport:     "tcp",
+		LocalAddress:  net.IPv4(127, 0, 0, 1).To4(),
+		LocalPort:     10248,
+		RemoteAddress: net.IPv4zero.To4(),
+		State:         tcpListeningState,
+		Inode:         31003,
+	}, sockets[0])
+	assert.Equal(t, "10.0.0.6", sockets[1].RemoteAddress.String())
+	assert.Equal(t, uint16(54321), sockets[1].RemotePort)
+
+	ip, port, err := parseProcNetAddress("00000000000000000000000001000000:094B")
+	require.NoError(t, err)
+	assert.Equal(t, "::1", ip.String())
+	assert.Equal(t, uint16(2379), port)
+}
+
+func Test_senseComponentEndpoints(t *testing.T) {
+	kubelet := &procspy.Proc{PID: 300, Name: "kubelet"}
+	res, err := senseComponentEndpoints(context.TODO(), "testdata/componentendpoints/proc", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []ComponentEndpoint{
+		{Component: "etcd", Description: "etcd client API", Transport: "tcp", Port: 2379, Address: "::1"},
+		{Component: "kubelet", Description: "kubelet healthz", Transport: "tcp", Port: 10248, Address: "127.0.0.1", Process: kubelet},
+		{Component: "kubelet", Description: "kubelet API", Transport: "tcp", Port: 10250, Address: "0.0.0.0", Exposed: true, Process: kubelet},
+		{Component: "kubelet", Description: "kubelet read-only API", Transport: "tcp", Port: 10255, Address: "0.0.0.0", Exposed: true,
+			Process: kubelet, Flag: "--read-only-port=10255"},
+		{Component: "kube-proxy", Description: "kube-proxy healthz", Transport: "tcp", Port: 10256, Address: "::", Exposed: true,
+			Process: &procspy.Proc{PID: 400, Name: "kube-proxy"}, Flag: "--healthz-bind-address=0.0.0.0:10256"},
+		{Component: "containerd", Description: "containerd metrics or CRI streaming", Transport: "tcp", Port: 41235, Address: "127.0.0.1",
+			Process: &procspy.Proc{PID: 42, Name: "containerd"}},
+	}, res.Endpoints)
+
+	_, err = senseComponentEndpoints(context.TODO(), "testdata/componentendpoints/proc", 2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package sensor
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// overlayConfFiles returns the config files with extension `ext` in `dirs` of the host file system at `rootDir`,
+// sorted by file name. As with modprobe.d and sysctl.d, a file overrides the files with the same name in the next dirs.
+func overlayConfFiles(rootDir string, dirs []string, ext string) []string {
+	files := map[string]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(path.Join(rootDir, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ext) {
+				continue
+			}
+			if _, overridden := files[entry.Name()]; !overridden {
+				files[entry.Name()] = path.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		res = append(res, files[name])
+	}
+	return res
+}
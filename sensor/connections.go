@@ -0,0 +1,195 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/weaveworks/procspy"
+)
+
+const (
+	// socket state of connected UDP sockets
+	udpEstablishedState = 1
+)
+
+// TCP states of /proc/net/tcp, as named by the kernel
+var tcpStateNames = map[uint8]string{
+	1:  "ESTABLISHED",
+	2:  "SYN_SENT",
+	3:  "SYN_RECV",
+	4:  "FIN_WAIT1",
+	5:  "FIN_WAIT2",
+	6:  "TIME_WAIT",
+	7:  "CLOSE",
+	8:  "CLOSE_WAIT",
+	9:  "LAST_ACK",
+	10: "LISTEN",
+	11: "CLOSING",
+	12: "NEW_SYN_RECV",
+}
+
+// NetConnection holds a TCP connection or a connected UDP socket of the host
+type NetConnection struct {
+	// tcp or udp
+	Transport string `json:"transport"`
+
+	LocalAddress  string `json:"localAddress"`
+	LocalPort     uint16 `json:"localPort"`
+	RemoteAddress string `json:"remoteAddress"`
+	RemotePort    uint16 `json:"remotePort"`
+
+	// Example: ESTABLISHED
+	State string `json:"state"`
+
+	// The process owning the socket (if found)
+	Process *procspy.Proc `json:"process,omitempty"`
+}
+
+// ConnectionsInfo holds the connections of the host network namespace
+type ConnectionsInfo struct {
+	Connections []NetConnection `json:"connections"`
+}
+
+// ConnectionsQuery filters the connections, see `ParseConnectionsQuery`
+type ConnectionsQuery struct {
+	// Socket states. By default all states but LISTEN are returned.
+	// Example: ["ESTABLISHED", "CLOSE_WAIT"]
+	States []string
+
+	// Port range, matching either the local or the remote port
+	MinPort uint16
+	MaxPort uint16
+
+	// Name or PID of the owning process
+	Process string
+}
+
+// ParseConnectionsQuery parses the `state`, `port` and `process` query parameters.
+// `state` is a comma separated list, `port` is a single port or a range (e.g. 6443 or 30000-32767).
+func ParseConnectionsQuery(values url.Values) (*ConnectionsQuery, error) {
+	query := ConnectionsQuery{MaxPort: 65535, Process: values.Get("process")}
+	badRequest := func(msg string) error {
+		return &SenseError{Massage: msg, Function: "ParseConnectionsQuery", Code: http.StatusBadRequest}
+	}
+
+	if states := values.Get("state"); states != "" {
+		known := map[string]bool{}
+		for _, name := range tcpStateNames {
+			known[name] = true
+		}
+		for _, state := range strings.Split(states, ",") {
+			state = strings.ToUpper(strings.TrimSpace(state))
+			if !known[state] {
+				return nil, badRequest(fmt.Sprintf("unknown state %q", state))
+			}
+			query.States = append(query.States, state)
+		}
+	}
+
+	if ports := values.Get("port"); ports != "" {
+		minPort, maxPort, isRange := strings.Cut(ports, "-")
+		if !isRange {
+			maxPort = minPort
+		}
+		minVal, err := strconv.ParseUint(minPort, 10, 16)
+		if err != nil {
+			return nil, badRequest(fmt.Sprintf("invalid port %q", ports))
+		}
+		maxVal, err := strconv.ParseUint(maxPort, 10, 16)
+		if err != nil || maxVal < minVal {
+			return nil, badRequest(fmt.Sprintf("invalid port %q", ports))
+		}
+		query.MinPort, query.MaxPort = uint16(minVal), uint16(maxVal)
+	}
+
+	return &query, nil
+}
+
+// match returns true if the connection matches the query
+func (q *ConnectionsQuery) match(conn *NetConnection) bool {
+	if len(q.States) == 0 {
+		if conn.State == tcpStateNames[tcpListeningState] {
+			return false
+		}
+	} else if !slices.Contains(q.States, conn.State) {
+		return false
+	}
+	inRange := func(port uint16) bool { return port >= q.MinPort && port <= q.MaxPort }
+	if !inRange(conn.LocalPort) && !inRange(conn.RemotePort) {
+		return false
+	}
+	if q.Process != "" {
+		if conn.Process == nil {
+			return false
+		}
+		if conn.Process.Name != q.Process && strconv.FormatUint(uint64(conn.Process.PID), 10) != q.Process {
+			return false
+		}
+	}
+	return true
+}
+
+// senseConnections returns the connections of the network namespace of process `pid` which match `query`
+func senseConnections(ctx context.Context, procDir string, pid int32, query *ConnectionsQuery) (*ConnectionsInfo, error) {
+	ret := ConnectionsInfo{Connections: make([]NetConnection, 0)}
+
+	sockets, err := readProcNetSockets(ctx, procNetPaths(procDir, pid, ProcNetTCPPaths), "tcp")
+	if err != nil {
+		return &ret, err
+	}
+	udpSockets, err := readProcNetSockets(ctx, procNetPaths(procDir, pid, ProcNetUDPPaths), "udp")
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseConnections failed to read UDP sockets", helpers.Error(err))
+	}
+	for _, socket := range udpSockets {
+		// only UDP sockets which are connected to a peer
+		if socket.State == udpEstablishedState {
+			sockets = append(sockets, socket)
+		}
+	}
+
+	owners, err := socketOwners(procDir)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseConnections failed to find sockets owners", helpers.Error(err))
+	}
+
+	for _, socket := range sockets {
+		conn := NetConnection{
+			Transport:     socket.Transport,
+			LocalAddress:  socket.LocalAddress.String(),
+			LocalPort:     socket.LocalPort,
+			RemoteAddress: socket.RemoteAddress.String(),
+			RemotePort:    socket.RemotePort,
+			State:         tcpStateNames[socket.State],
+		}
+		// sockets in TIME_WAIT have no inode
+		if owner, ok := owners[socket.Inode]; ok && socket.Inode != 0 {
+			conn.Process = &owner
+		}
+		if query.match(&conn) {
+			ret.Connections = append(ret.Connections, conn)
+		}
+	}
+
+	sort.SliceStable(ret.Connections, func(i, j int) bool {
+		if ret.Connections[i].RemoteAddress != ret.Connections[j].RemoteAddress {
+			return ret.Connections[i].RemoteAddress < ret.Connections[j].RemoteAddress
+		}
+		return ret.Connections[i].RemotePort < ret.Connections[j].RemotePort
+	})
+
+	return &ret, nil
+}
+
+// SenseConnections returns the TCP connections and the connected UDP sockets of the host, with their owning processes
+func SenseConnections(ctx context.Context, query *ConnectionsQuery) (*ConnectionsInfo, error) {
+	return senseConnections(ctx, procDirName, hostNetNsPID, query)
+}
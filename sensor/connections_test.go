@@ -0,0 +1,102 @@
+package sensor
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/procspy"
+)
+
+func TestParseConnectionsQuery(t *testing.T) {
+	query, err := ParseConnectionsQuery(url.Values{"state": {"established, close_wait"}, "port": {"30000-32767"}, "process": {"kubelet"}})
+	require.NoError(t, err)
+	assert.Equal(t, &ConnectionsQuery{States: []string{"ESTABLISHED", "CLOSE_WAIT"}, MinPort: 30000, MaxPort: 32767, Process: "kubelet"}, query)
+
+	query, err = ParseConnectionsQuery(url.Values{"port": {"443"}})
+	require.NoError(t, err)
+	assert.Equal(t, &ConnectionsQuery{MinPort: 443, MaxPort: 443}, query)
+
+	for _, values := range []url.Values{
+		{"state": {"CONNECTED"}},
+		{"port": {"abc"}},
+		{"port": {"2000-1000"}},
+		{"port": {"70000"}},
+	} {
+		_, err := ParseConnectionsQuery(values)
+		assert.Error(t, err, values)
+	}
+}
+
+func Test_senseConnections(t *testing.T) {
+	kubelet := &procspy.Proc{PID: 500, Name: "kubelet"}
+	curl := &procspy.Proc{PID: 600, Name: "curl"}
+	tests := []struct {
+		name   string
+		values url.Values
+		want   []NetConnection
+	}{
+		{
+			name: "all but listening",
+			want: []NetConnection{
+				{Transport: "tcp", LocalAddress: "10.0.0.5", LocalPort: 40001, RemoteAddress: "1.2.3.4", RemotePort: 443, State: "TIME_WAIT"},
+				{Transport: "tcp", LocalAddress: "10.0.0.5", LocalPort: 51234, RemoteAddress: "10.96.0.1", RemotePort: 443, State: "ESTABLISHED", Process: kubelet},
+				{Transport: "udp", LocalAddress: "10.0.0.5", LocalPort: 53000, RemoteAddress: "10.96.0.10", RemotePort: 53, State: "ESTABLISHED", Process: curl},
+				{Transport: "tcp", LocalAddress: "10.0.0.5", LocalPort: 40000, RemoteAddress: "169.254.169.254", RemotePort: 80, State: "ESTABLISHED", Process: curl},
+			},
+		},
+		{
+			name:   "listening",
+			values: url.Values{"state": {"listen"}},
+			want: []NetConnection{
+				{Transport: "tcp", LocalAddress: "0.0.0.0", LocalPort: 10250, RemoteAddress: "0.0.0.0", State: "LISTEN", Process: kubelet},
+			},
+		},
+		{
+			name:   "process and port",
+			values: url.Values{"process": {"600"}, "port": {"80"}},
+			want: []NetConnection{
+				{Transport: "tcp", LocalAddress: "10.0.0.5", LocalPort: 40000, RemoteAddress: "169.254.169.254", RemotePort: 80, State: "ESTABLISHED", Process: curl},
+			},
+		},
+		{
+			name:   "established to the API server",
+			values: url.Values{"state": {"ESTABLISHED"}, "port": {"443"}},
+			want: []NetConnection{
+				{Transport: "tcp", LocalAddress: "10.0.0.5", LocalPort: 51234, RemoteAddress: "10.96.0.1", RemotePort: 443, State: "ESTABLISHED", Process: kubelet},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseConnectionsQuery(tt.values)
+			require.NoError(t, err)
+			res, err := senseConnections(context.TODO(), "testdata/connections/proc", 1, query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res.Connections)
+		})
+	}
+}
+
+func Test_senseConnectionsWithoutIPv6(t *testing.T) {
+	// no tcp6 and udp6 files when IPv6 is disabled
+	procDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(procDir, "1/net"), 0o755))
+	for _, name := range []string{"tcp", "udp"} {
+		content, err := os.ReadFile(path.Join("testdata/connections/proc/1/net", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path.Join(procDir, "1/net", name), content, 0o644))
+	}
+	query, err := ParseConnectionsQuery(url.Values{"state": {"listen"}})
+	require.NoError(t, err)
+	res, err := senseConnections(context.TODO(), procDir, 1, query)
+	require.NoError(t, err)
+	assert.Len(t, res.Connections, 1)
+
+	_, err = senseConnections(context.TODO(), procDir, 2, query)
+	assert.Error(t, err)
+}
@@ -1,6 +1,121 @@
 package datastructures
 
 type LinuxSecurityHardeningStatus struct {
+	// The raw content of /sys/kernel/security/apparmor/profiles, "unloaded" or "stopped"
 	AppArmor string `json:"appArmor"`
-	SeLinux  string `json:"seLinux"`
+
+	AppArmorStatus *AppArmorStatus `json:"appArmorStatus,omitempty"`
+
+	// The SELinux mode: enforcing, permissive, disabled or "not found"
+	SeLinux string `json:"seLinux"`
+
+	SeLinuxStatus *SELinuxStatus `json:"seLinuxStatus,omitempty"`
+
+	// The active LSM stack, from /sys/kernel/security/lsm
+	// Example: ["lockdown", "capability", "yama", "apparmor"]
+	LSMs []string `json:"lsms,omitempty"`
+
+	// The kernel lockdown mode: none, integrity or confidentiality
+	Lockdown string `json:"lockdown,omitempty"`
+
+	// The Yama ptrace_scope, from /proc/sys/kernel/yama/ptrace_scope (if Yama is enabled)
+	YamaPtraceScope *int `json:"yamaPtraceScope,omitempty"`
+
+	Seccomp *SeccompStatus `json:"seccomp,omitempty"`
+}
+
+// SeccompStatus holds the seccomp support of the kernel and the seccomp state of the node critical processes
+type SeccompStatus struct {
+	// true if the kernel supports seccomp
+	Supported bool `json:"supported"`
+
+	// true if the kernel supports seccomp filters (seccomp-bpf)
+	FilterSupported bool `json:"filterSupported"`
+
+	// The available filter actions, from /proc/sys/kernel/seccomp/actions_avail
+	// Example: ["kill_process", "kill_thread", "trap", "errno", "user_notif", "trace", "log", "allow"]
+	Actions []string `json:"actions,omitempty"`
+
+	// The seccomp state of the kubelet and the container runtime processes
+	Processes []ProcessSeccomp `json:"processes,omitempty"`
+}
+
+// ProcessSeccomp holds the seccomp state of a process, from /proc/<pid>/status
+type ProcessSeccomp struct {
+	// Example: kubelet
+	Name string `json:"name"`
+	PID  int32  `json:"pid"`
+
+	// disabled, strict or filter
+	Mode string `json:"mode"`
+
+	// The number of attached filters (on kernels which report it)
+	Filters *int `json:"filters,omitempty"`
+}
+
+// AppArmorStatus holds the loaded AppArmor profiles and the processes they confine
+type AppArmorStatus struct {
+	Profiles []AppArmorProfile `json:"profiles"`
+
+	// The number of loaded profiles per mode
+	// Example: {"enforce": 42, "complain": 3}
+	ModeCounts map[string]int `json:"modeCounts"`
+
+	// The default profiles of the container runtimes: cri-containerd.apparmor.d and crio-default
+	RuntimeProfiles []AppArmorRuntimeProfile `json:"runtimeProfiles"`
+
+	// The running processes which are confined by a profile
+	ConfinedProcesses []ProcessSecurityLabel `json:"confinedProcesses"`
+}
+
+// AppArmorProfile holds a loaded AppArmor profile
+type AppArmorProfile struct {
+	// Example: cri-containerd.apparmor.d
+	Name string `json:"name"`
+
+	// enforce, complain, kill or unconfined
+	Mode string `json:"mode"`
+}
+
+// AppArmorRuntimeProfile holds the state of the default profile of a container runtime
+type AppArmorRuntimeProfile struct {
+	Name      string `json:"name"`
+	Loaded    bool   `json:"loaded"`
+	Enforcing bool   `json:"enforcing"`
+}
+
+// SELinuxStatus holds the runtime and configured state of SELinux
+type SELinuxStatus struct {
+	// true if the selinuxfs is mounted, i.e. SELinux is enabled in the kernel
+	Enabled bool `json:"enabled"`
+
+	// The runtime mode, from /sys/fs/selinux/enforce: enforcing, permissive or disabled
+	Mode string `json:"mode"`
+
+	// The SELINUX value of /etc/selinux/config (if found)
+	// Example: enforcing
+	ConfigMode string `json:"configMode,omitempty"`
+
+	// The SELINUXTYPE value of /etc/selinux/config (if found)
+	// Example: targeted
+	PolicyType string `json:"policyType,omitempty"`
+
+	// The version of the loaded policy, from /sys/fs/selinux/policyvers
+	PolicyVersion string `json:"policyVersion,omitempty"`
+
+	// true if the runtime mode is the configured one, nil if the config was not found
+	ModesAgree *bool `json:"modesAgree,omitempty"`
+
+	// The SELinux contexts of the kubelet and the container runtime processes
+	ProcessContexts []ProcessSecurityLabel `json:"processContexts,omitempty"`
+}
+
+// ProcessSecurityLabel holds the LSM label of a process
+type ProcessSecurityLabel struct {
+	// Example: kubelet
+	Name string `json:"name"`
+	PID  int32  `json:"pid"`
+
+	// Example: system_u:system_r:kernel_t:s0
+	Label string `json:"label"`
 }
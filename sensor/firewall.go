@@ -0,0 +1,474 @@
+package sensor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+const (
+	firewallBackendNFTables       = "nftables"
+	firewallBackendIPTablesLegacy = "iptables-legacy"
+
+	// sizes of the iptables getsockopt structures (see linux/netfilter_ipv4/ip_tables.h)
+	iptGetInfoSize       = 84
+	iptGetEntriesHdrSize = 40
+	xtTableMaxNameLen    = 32
+	xtExtensionMaxName   = 29
+	xtEntryHeaderSize    = 32
+
+	// the goto values of ipt_ip.flags and ip6t_ip6.flags (where 0x02 is IP6T_F_TOS)
+	iptFlagGoto  = 0x02
+	ip6tFlagGoto = 0x04
+
+	// ipt_ip.invflags and ip6t_ip6.invflags values
+	iptInvViaIn      = 0x01
+	iptInvViaOut     = 0x02
+	iptInvSrcIP      = 0x08
+	iptInvDstIP      = 0x10
+	iptInvProto      = 0x40
+	xtStandardTarget = ""
+	xtErrorTarget    = "ERROR"
+	iptNumberOfHooks = 5
+	iptVerdictDrop   = -1
+	iptVerdictAccept = -2
+	iptVerdictQueue  = -4
+	iptVerdictReturn = -5
+)
+
+var (
+	ErrFirewallNotSupported = errors.New("reading firewall rules is not supported on this OS")
+
+	// legacy iptables tables to collect
+	iptablesTables = []string{"filter", "nat", "mangle", "raw"}
+
+	// iptables builtin chains by hook number
+	iptablesHookNames = [iptNumberOfHooks]string{"PREROUTING", "INPUT", "FORWARD", "OUTPUT", "POSTROUTING"}
+
+	ipProtocolNames = map[uint16]string{
+		1:   "icmp",
+		6:   "tcp",
+		17:  "udp",
+		47:  "gre",
+		50:  "esp",
+		58:  "ipv6-icmp",
+		132: "sctp",
+	}
+)
+
+// FirewallInfo holds the firewall rules of the host
+type FirewallInfo struct {
+	Tables []FirewallTable `json:"tables"`
+}
+
+// FirewallTable holds a single firewall table
+type FirewallTable struct {
+	// The backend the table was read from (nftables or iptables-legacy)
+	Backend string `json:"backend"`
+
+	// The address family of the table (ip, ip6, inet, arp, bridge or netdev)
+	Family string `json:"family"`
+
+	// Example: filter
+	Name string `json:"name"`
+
+	Chains []FirewallChain `json:"chains"`
+}
+
+// FirewallChain holds a single firewall chain
+type FirewallChain struct {
+	// Example: INPUT
+	Name string `json:"name"`
+
+	// The netfilter hook of a base chain (empty for regular chains)
+	Hook string `json:"hook,omitempty"`
+
+	// The type of an nftables base chain (filter, nat or route)
+	Type string `json:"type,omitempty"`
+
+	// The default policy of a base chain
+	// Example: ACCEPT
+	Policy string `json:"policy,omitempty"`
+
+	Rules []FirewallRule `json:"rules"`
+}
+
+// FirewallRule holds a single firewall rule
+type FirewallRule struct {
+	// Readable specification of the rule.
+	// Example: `-s 10.0.0.0/8 -i eth0 -p tcp -m conntrack -j ACCEPT`
+	Spec string `json:"spec"`
+
+	// Names of the match extensions used by the rule
+	Matches []string `json:"matches,omitempty"`
+
+	// The verdict or target of the rule.
+	// Example: ACCEPT, DROP, KUBE-SERVICES or DNAT
+	Target string `json:"target,omitempty"`
+}
+
+// iptablesFamily describes the layout of the iptables entries of an address family
+type iptablesFamily struct {
+	// Example: ip
+	name string
+
+	// length of an address in the entries
+	addrLen int
+
+	// sizeof(struct ipt_entry)
+	entrySize int
+
+	// offset of the protocol in the entry
+	protoOffset int
+
+	// offset of the inversion flags in the entry, which follow the flags
+	invFlagsOffset int
+
+	// the flag of a goto (`-g`) rule
+	gotoFlag byte
+
+	// offset of the target_offset field in the entry
+	targetOffset int
+}
+
+var (
+	// struct ipt_entry
+	iptablesFamilyIPv4 = iptablesFamily{name: "ip", addrLen: 4, entrySize: 112, protoOffset: 80, invFlagsOffset: 83, gotoFlag: iptFlagGoto, targetOffset: 88}
+	// struct ip6t_entry
+	iptablesFamilyIPv6 = iptablesFamily{name: "ip6", addrLen: 16, entrySize: 168, protoOffset: 128, invFlagsOffset: 132, gotoFlag: ip6tFlagGoto, targetOffset: 140}
+)
+
+// iptablesInfo is the decoded `struct ipt_getinfo`
+type iptablesInfo struct {
+	ValidHooks uint32
+	HookEntry  [iptNumberOfHooks]uint32
+	Underflow  [iptNumberOfHooks]uint32
+	NumEntries uint32
+	Size       uint32
+}
+
+// firewallReader reads the firewall rulesets of the host.
+// The decoding is done separately so it can be tested with fixtures, without root.
+type firewallReader interface {
+	// nfTables returns the nftables ruleset
+	nfTables() ([]FirewallTable, error)
+
+	// iptablesTable returns the raw replies of IPT_SO_GET_INFO and IPT_SO_GET_ENTRIES for a legacy table
+	iptablesTable(family iptablesFamily, table string) (info []byte, entries []byte, err error)
+
+	// close releases the reader resources
+	close()
+}
+
+// decodeIPTablesInfo decodes a `struct ipt_getinfo`
+func decodeIPTablesInfo(b []byte) (*iptablesInfo, error) {
+	if len(b) < iptGetInfoSize {
+		return nil, fmt.Errorf("ipt_getinfo is too short: %d", len(b))
+	}
+	info := iptablesInfo{}
+	info.ValidHooks = binary.NativeEndian.Uint32(b[32:])
+	for i := 0; i < iptNumberOfHooks; i++ {
+		info.HookEntry[i] = binary.NativeEndian.Uint32(b[36+4*i:])
+		info.Underflow[i] = binary.NativeEndian.Uint32(b[56+4*i:])
+	}
+	info.NumEntries = binary.NativeEndian.Uint32(b[76:])
+	info.Size = binary.NativeEndian.Uint32(b[80:])
+	return &info, nil
+}
+
+// iptablesEntry is a decoded `struct ipt_entry`
+type iptablesEntry struct {
+	offset     int
+	nextOffset int
+	flags      byte
+	rule       FirewallRule
+
+	// the standard target verdict, or the name of an error target
+	verdict   int32
+	errorName string
+	isError   bool
+	isStd     bool
+}
+
+// cString returns a string out of a NUL terminated buffer
+func cString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		return string(b[:i])
+	}
+	return string(b)
+}
+
+// decodeIPTablesEntry decodes the entry at the beginning of `b`
+func decodeIPTablesEntry(family iptablesFamily, b []byte, offset int) (*iptablesEntry, error) {
+	if len(b) < family.entrySize {
+		return nil, fmt.Errorf("entry at %d is too short", offset)
+	}
+	targetOffset := int(binary.NativeEndian.Uint16(b[family.targetOffset:]))
+	nextOffset := int(binary.NativeEndian.Uint16(b[family.targetOffset+2:]))
+	if targetOffset < family.entrySize || nextOffset < targetOffset+xtEntryHeaderSize || nextOffset > len(b) {
+		return nil, fmt.Errorf("entry at %d has invalid offsets", offset)
+	}
+
+	entry := iptablesEntry{offset: offset, nextOffset: nextOffset}
+	entry.flags = b[family.invFlagsOffset-1]
+	invFlags := b[family.invFlagsOffset]
+	spec := []string{}
+
+	// addresses
+	addrLen := family.addrLen
+	addrs := []struct {
+		flag   string
+		addr   []byte
+		mask   []byte
+		invert byte
+	}{
+		{"-s", b[0:addrLen], b[2*addrLen : 3*addrLen], iptInvSrcIP},
+		{"-d", b[addrLen : 2*addrLen], b[3*addrLen : 4*addrLen], iptInvDstIP},
+	}
+	for _, a := range addrs {
+		ones, _ := net.IPMask(a.mask).Size()
+		if ones == 0 && net.IP(a.addr).IsUnspecified() {
+			continue
+		}
+		spec = append(spec, invertSpec(invFlags&a.invert != 0, a.flag, fmt.Sprintf("%s/%d", net.IP(a.addr), ones))...)
+	}
+
+	// interfaces
+	ifaces := 4 * addrLen
+	for i, iface := range []struct {
+		flag   string
+		invert byte
+	}{{"-i", iptInvViaIn}, {"-o", iptInvViaOut}} {
+		name := cString(b[ifaces+16*i : ifaces+16*(i+1)])
+		if name == "" {
+			continue
+		}
+		// the mask covers only the prefix of a wildcard interface
+		mask := b[ifaces+32+16*i : ifaces+32+16*(i+1)]
+		if len(name) < 16 && mask[len(name)] == 0 {
+			name += "+"
+		}
+		spec = append(spec, invertSpec(invFlags&iface.invert != 0, iface.flag, name)...)
+	}
+
+	// protocol
+	if proto := binary.NativeEndian.Uint16(b[family.protoOffset:]); proto != 0 {
+		protoName, ok := ipProtocolNames[proto]
+		if !ok {
+			protoName = strconv.Itoa(int(proto))
+		}
+		spec = append(spec, invertSpec(invFlags&iptInvProto != 0, "-p", protoName)...)
+	}
+
+	// matches
+	for m := family.entrySize; m+xtEntryHeaderSize <= targetOffset; {
+		matchSize := int(binary.NativeEndian.Uint16(b[m:]))
+		if matchSize < xtEntryHeaderSize {
+			return nil, fmt.Errorf("entry at %d has invalid match size", offset)
+		}
+		name := cString(b[m+2 : m+2+xtExtensionMaxName])
+		entry.rule.Matches = append(entry.rule.Matches, name)
+		spec = append(spec, "-m", name)
+		m += matchSize
+	}
+
+	// target
+	target := b[targetOffset:nextOffset]
+	targetName := cString(target[2 : 2+xtExtensionMaxName])
+	switch targetName {
+	case xtStandardTarget:
+		if len(target) < xtEntryHeaderSize+4 {
+			return nil, fmt.Errorf("entry at %d has invalid standard target", offset)
+		}
+		entry.isStd = true
+		entry.verdict = int32(binary.NativeEndian.Uint32(target[xtEntryHeaderSize:]))
+	case xtErrorTarget:
+		entry.isError = true
+		entry.errorName = cString(target[xtEntryHeaderSize:])
+	default:
+		entry.rule.Target = targetName
+	}
+
+	entry.rule.Spec = strings.Join(spec, " ")
+	return &entry, nil
+}
+
+func invertSpec(invert bool, flag string, value string) []string {
+	if invert {
+		return []string{"!", flag, value}
+	}
+	return []string{flag, value}
+}
+
+// iptablesVerdictName returns the name of a negative standard verdict
+func iptablesVerdictName(verdict int32) string {
+	switch verdict {
+	case iptVerdictAccept:
+		return "ACCEPT"
+	case iptVerdictDrop:
+		return "DROP"
+	case iptVerdictQueue:
+		return "QUEUE"
+	case iptVerdictReturn:
+		return "RETURN"
+	}
+	return strconv.Itoa(int(verdict))
+}
+
+// decodeIPTablesEntries decodes the entries blob of a legacy iptables table into chains
+func decodeIPTablesEntries(family iptablesFamily, info *iptablesInfo, blob []byte) ([]FirewallChain, error) {
+	entries := []*iptablesEntry{}
+	for offset := 0; offset < len(blob); {
+		entry, err := decodeIPTablesEntry(family, blob[offset:], offset)
+		if err != nil {
+			return nil, err
+		}
+		entry.nextOffset += offset
+		entries = append(entries, entry)
+		offset = entry.nextOffset
+	}
+
+	// chains starting offsets, used for resolving jumps
+	chainByOffset := map[int]string{}
+	builtinByOffset := map[int]int{}
+	policyOffsets := map[int]bool{}
+	for hook := 0; hook < iptNumberOfHooks; hook++ {
+		if info.ValidHooks&(1<<hook) == 0 {
+			continue
+		}
+		chainByOffset[int(info.HookEntry[hook])] = iptablesHookNames[hook]
+		builtinByOffset[int(info.HookEntry[hook])] = hook
+		policyOffsets[int(info.Underflow[hook])] = true
+	}
+	for i, entry := range entries {
+		if entry.isError && entry.errorName != xtErrorTarget && i+1 < len(entries) {
+			// jumps point to the first rule of a user defined chain
+			chainByOffset[entries[i+1].offset] = entry.errorName
+		}
+	}
+
+	chains := []FirewallChain{}
+	var current *FirewallChain
+	isUserChain := false
+	endChain := func() {
+		// user defined chains end with an implicit RETURN rule
+		if current != nil && isUserChain && len(current.Rules) > 0 {
+			current.Rules = current.Rules[:len(current.Rules)-1]
+		}
+		current = nil
+	}
+	for _, entry := range entries {
+		if hook, ok := builtinByOffset[entry.offset]; ok {
+			endChain()
+			chains = append(chains, FirewallChain{Name: iptablesHookNames[hook], Hook: iptablesHookNames[hook],
+				Rules: []FirewallRule{}})
+			current = &chains[len(chains)-1]
+			isUserChain = false
+		}
+		if entry.isError {
+			endChain()
+			if entry.errorName == xtErrorTarget {
+				break
+			}
+			chains = append(chains, FirewallChain{Name: entry.errorName, Rules: []FirewallRule{}})
+			current = &chains[len(chains)-1]
+			isUserChain = true
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if entry.isStd {
+			if policyOffsets[entry.offset] && !isUserChain {
+				current.Policy = iptablesVerdictName(entry.verdict)
+				continue
+			}
+			switch {
+			case entry.verdict < 0:
+				entry.rule.Target = iptablesVerdictName(entry.verdict)
+			case int(entry.verdict) == entry.nextOffset:
+				// falls through to the next rule, no target
+			default:
+				entry.rule.Target = chainByOffset[int(entry.verdict)]
+			}
+		}
+		if entry.rule.Target != "" {
+			jump := "-j"
+			if entry.flags&family.gotoFlag != 0 {
+				jump = "-g"
+			}
+			entry.rule.Spec = strings.TrimSpace(entry.rule.Spec + " " + jump + " " + entry.rule.Target)
+		}
+		current.Rules = append(current.Rules, entry.rule)
+	}
+	endChain()
+
+	return chains, nil
+}
+
+// readIPTablesTables reads and decodes the legacy iptables tables
+func readIPTablesTables(ctx context.Context, reader firewallReader) []FirewallTable {
+	res := []FirewallTable{}
+	for _, family := range []iptablesFamily{iptablesFamilyIPv4, iptablesFamilyIPv6} {
+		for _, table := range iptablesTables {
+			rawInfo, rawEntries, err := reader.iptablesTable(family, table)
+			if err != nil {
+				// the table module is not loaded, or the host is using iptables-nft
+				logger.L().Debug("failed to read iptables table", helpers.String("family", family.name),
+					helpers.String("table", table), helpers.Error(err))
+				continue
+			}
+			info, err := decodeIPTablesInfo(rawInfo)
+			if err != nil {
+				logger.L().Ctx(ctx).Warning("failed to decode iptables table info", helpers.String("table", table), helpers.Error(err))
+				continue
+			}
+			chains, err := decodeIPTablesEntries(family, info, rawEntries)
+			if err != nil {
+				logger.L().Ctx(ctx).Warning("failed to decode iptables table", helpers.String("table", table), helpers.Error(err))
+				continue
+			}
+			res = append(res, FirewallTable{
+				Backend: firewallBackendIPTablesLegacy,
+				Family:  family.name,
+				Name:    table,
+				Chains:  chains,
+			})
+		}
+	}
+	return res
+}
+
+// senseFirewallRules collects the nftables and legacy iptables rulesets using `reader`
+func senseFirewallRules(ctx context.Context, reader firewallReader) *FirewallInfo {
+	ret := FirewallInfo{Tables: []FirewallTable{}}
+
+	nfTables, err := reader.nfTables()
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseFirewallRules failed to read nftables", helpers.Error(err))
+	} else {
+		ret.Tables = append(ret.Tables, nfTables...)
+	}
+
+	ret.Tables = append(ret.Tables, readIPTablesTables(ctx, reader)...)
+
+	return &ret
+}
+
+// SenseFirewallRules returns the nftables and legacy iptables rules of the host network namespace
+func SenseFirewallRules(ctx context.Context) (*FirewallInfo, error) {
+	reader, err := newHostFirewallReader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read firewall rules: %w", err)
+	}
+	defer reader.close()
+
+	return senseFirewallRules(ctx, reader), nil
+}
@@ -0,0 +1,357 @@
+package sensor
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unsafe"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// getsockopt options of ip_tables and ip6_tables
+	iptSoGetInfo    = 64
+	iptSoGetEntries = 65
+)
+
+var (
+	nftFamilyNames = map[nftables.TableFamily]string{
+		nftables.TableFamilyINet:   "inet",
+		nftables.TableFamilyIPv4:   "ip",
+		nftables.TableFamilyIPv6:   "ip6",
+		nftables.TableFamilyARP:    "arp",
+		nftables.TableFamilyNetdev: "netdev",
+		nftables.TableFamilyBridge: "bridge",
+	}
+
+	nftHookNames = map[nftables.ChainHook]string{
+		*nftables.ChainHookPrerouting:  "prerouting",
+		*nftables.ChainHookInput:       "input",
+		*nftables.ChainHookForward:     "forward",
+		*nftables.ChainHookOutput:      "output",
+		*nftables.ChainHookPostrouting: "postrouting",
+	}
+
+	nftMetaKeyNames = map[expr.MetaKey]string{
+		expr.MetaKeyLEN:      "length",
+		expr.MetaKeyPROTOCOL: "protocol",
+		expr.MetaKeyMARK:     "mark",
+		expr.MetaKeyIIF:      "iif",
+		expr.MetaKeyOIF:      "oif",
+		expr.MetaKeyIIFNAME:  "iifname",
+		expr.MetaKeyOIFNAME:  "oifname",
+		expr.MetaKeyIIFTYPE:  "iiftype",
+		expr.MetaKeyOIFTYPE:  "oiftype",
+		expr.MetaKeySKUID:    "skuid",
+		expr.MetaKeySKGID:    "skgid",
+		expr.MetaKeyNFPROTO:  "nfproto",
+		expr.MetaKeyL4PROTO:  "l4proto",
+		expr.MetaKeyPKTTYPE:  "pkttype",
+		expr.MetaKeyCGROUP:   "cgroup",
+	}
+
+	nftCtKeyNames = map[expr.CtKey]string{
+		expr.CtKeySTATE:     "state",
+		expr.CtKeyDIRECTION: "direction",
+		expr.CtKeySTATUS:    "status",
+		expr.CtKeyMARK:      "mark",
+		expr.CtKeySRC:       "saddr",
+		expr.CtKeyDST:       "daddr",
+		expr.CtKeyPROTODST:  "proto-dst",
+	}
+
+	nftCmpOpNames = map[expr.CmpOp]string{
+		expr.CmpOpEq:  "==",
+		expr.CmpOpNeq: "!=",
+		expr.CmpOpLt:  "<",
+		expr.CmpOpLte: "<=",
+		expr.CmpOpGt:  ">",
+		expr.CmpOpGte: ">=",
+	}
+
+	nftPayloadBaseNames = map[expr.PayloadBase]string{
+		expr.PayloadBaseLLHeader:        "link",
+		expr.PayloadBaseNetworkHeader:   "network",
+		expr.PayloadBaseTransportHeader: "transport",
+	}
+)
+
+// hostFirewallReader reads the firewall rulesets of the host network namespace
+type hostFirewallReader struct {
+	netNs *os.File
+}
+
+func newHostFirewallReader() (firewallReader, error) {
+	netNs, err := os.Open(hostNetNsProcPath("ns", "net"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open host network namespace: %w", err)
+	}
+	return &hostFirewallReader{netNs: netNs}, nil
+}
+
+func (r *hostFirewallReader) close() {
+	r.netNs.Close()
+}
+
+// nfTables reads the nftables ruleset over netlink
+func (r *hostFirewallReader) nfTables() ([]FirewallTable, error) {
+	conn, err := nftables.New(nftables.WithNetNSFd(int(r.netNs.Fd())))
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := conn.ListTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	chains, err := conn.ListChains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chains: %w", err)
+	}
+	rules := make(map[*nftables.Chain][]*nftables.Rule, len(chains))
+	for _, chain := range chains {
+		rules[chain], err = conn.GetRules(chain.Table, chain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rules of chain %s: %w", chain.Name, err)
+		}
+	}
+
+	return decodeNFTRuleset(tables, chains, rules), nil
+}
+
+// iptablesTable reads a legacy iptables table with getsockopt
+func (r *hostFirewallReader) iptablesTable(family iptablesFamily, table string) ([]byte, []byte, error) {
+	domain, level := unix.AF_INET, unix.SOL_IP
+	if family.name == iptablesFamilyIPv6.name {
+		domain, level = unix.AF_INET6, unix.SOL_IPV6
+	}
+
+	fd, err := r.rawSocket(domain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open raw socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	rawInfo := make([]byte, iptGetInfoSize)
+	copy(rawInfo[:xtTableMaxNameLen-1], table)
+	if err := getsockopt(fd, level, iptSoGetInfo, rawInfo); err != nil {
+		return nil, nil, fmt.Errorf("failed to get table info: %w", err)
+	}
+	info, err := decodeIPTablesInfo(rawInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawEntries := make([]byte, iptGetEntriesHdrSize+int(info.Size))
+	copy(rawEntries[:xtTableMaxNameLen-1], table)
+	binary.NativeEndian.PutUint32(rawEntries[xtTableMaxNameLen:], info.Size)
+	if err := getsockopt(fd, level, iptSoGetEntries, rawEntries); err != nil {
+		return nil, nil, fmt.Errorf("failed to get table entries: %w", err)
+	}
+
+	return rawInfo, rawEntries[iptGetEntriesHdrSize:], nil
+}
+
+// rawSocket opens a raw socket in the host network namespace.
+// Sockets stay in the namespace they were created in, so only the creation is done in the host namespace.
+func (r *hostFirewallReader) rawSocket(domain int) (int, error) {
+	fd := -1
+	err := utils.RunInNetNs(hostNetNsProcPath("ns", "net"), func() error {
+		var err error
+		fd, err = unix.Socket(domain, unix.SOCK_RAW, unix.IPPROTO_RAW)
+		return err
+	})
+	if err != nil && fd >= 0 {
+		unix.Close(fd)
+	}
+	return fd, err
+}
+
+func getsockopt(fd, level, opt int, buf []byte) error {
+	size := uint32(len(buf))
+	_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), uintptr(level), uintptr(opt),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// decodeNFTRuleset converts the nftables objects into firewall tables
+func decodeNFTRuleset(tables []*nftables.Table, chains []*nftables.Chain, rules map[*nftables.Chain][]*nftables.Rule) []FirewallTable {
+	res := make([]FirewallTable, 0, len(tables))
+	tableIdx := map[string]int{}
+	for _, table := range tables {
+		tableIdx[nftTableKey(table)] = len(res)
+		res = append(res, FirewallTable{
+			Backend: firewallBackendNFTables,
+			Family:  nftFamilyNames[table.Family],
+			Name:    table.Name,
+			Chains:  []FirewallChain{},
+		})
+	}
+
+	for _, chain := range chains {
+		idx, ok := tableIdx[nftTableKey(chain.Table)]
+		if !ok {
+			continue
+		}
+		fwChain := FirewallChain{Name: chain.Name, Type: string(chain.Type), Rules: []FirewallRule{}}
+		if chain.Hooknum != nil {
+			fwChain.Hook = nftHookNames[*chain.Hooknum]
+			if chain.Table.Family == nftables.TableFamilyNetdev {
+				fwChain.Hook = "ingress"
+			}
+		}
+		if chain.Policy != nil {
+			fwChain.Policy = "accept"
+			if *chain.Policy == nftables.ChainPolicyDrop {
+				fwChain.Policy = "drop"
+			}
+		}
+		for _, rule := range rules[chain] {
+			fwChain.Rules = append(fwChain.Rules, decodeNFTRule(rule))
+		}
+		res[idx].Chains = append(res[idx].Chains, fwChain)
+	}
+
+	return res
+}
+
+func nftTableKey(table *nftables.Table) string {
+	if table == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", table.Family, table.Name)
+}
+
+// decodeNFTRule converts the rule expressions into a readable rule
+func decodeNFTRule(rule *nftables.Rule) FirewallRule {
+	res := FirewallRule{}
+	spec := make([]string, 0, len(rule.Exprs))
+	for _, e := range rule.Exprs {
+		switch e := e.(type) {
+		case *expr.Match:
+			res.Matches = append(res.Matches, e.Name)
+		case *expr.Target:
+			res.Target = e.Name
+		case *expr.Verdict:
+			res.Target = nftVerdictString(e)
+		case *expr.NAT:
+			res.Target = "snat"
+			if e.Type == expr.NATTypeDestNAT {
+				res.Target = "dnat"
+			}
+		case *expr.Masq:
+			res.Target = "masquerade"
+		case *expr.Redir:
+			res.Target = "redirect"
+		case *expr.Reject:
+			res.Target = "reject"
+		}
+		spec = append(spec, nftExprString(e))
+	}
+	res.Spec = strings.Join(spec, " ")
+	return res
+}
+
+// nftExprString returns a readable (register level) representation of an expression
+func nftExprString(e expr.Any) string {
+	switch e := e.(type) {
+	case *expr.Meta:
+		if name, ok := nftMetaKeyNames[e.Key]; ok {
+			return "meta " + name
+		}
+		return fmt.Sprintf("meta %d", e.Key)
+	case *expr.Ct:
+		if name, ok := nftCtKeyNames[e.Key]; ok {
+			return "ct " + name
+		}
+		return fmt.Sprintf("ct %d", e.Key)
+	case *expr.Cmp:
+		return fmt.Sprintf("%s %s", nftCmpOpNames[e.Op], nftDataString(e.Data))
+	case *expr.Payload:
+		return fmt.Sprintf("payload %s+%d/%d", nftPayloadBaseNames[e.Base], e.Offset, e.Len)
+	case *expr.Bitwise:
+		return fmt.Sprintf("& %s ^ %s", nftDataString(e.Mask), nftDataString(e.Xor))
+	case *expr.Lookup:
+		if e.Invert {
+			return "!= @" + e.SetName
+		}
+		return "@" + e.SetName
+	case *expr.Immediate:
+		return "immediate " + nftDataString(e.Data)
+	case *expr.Counter:
+		return "counter"
+	case *expr.Log:
+		return "log"
+	case *expr.Limit:
+		return "limit"
+	case *expr.Notrack:
+		return "notrack"
+	case *expr.Match:
+		return "xt match " + e.Name
+	case *expr.Target:
+		return "xt target " + e.Name
+	case *expr.Verdict:
+		return nftVerdictString(e)
+	case *expr.NAT:
+		if e.Type == expr.NATTypeDestNAT {
+			return "dnat"
+		}
+		return "snat"
+	case *expr.Masq:
+		return "masquerade"
+	case *expr.Redir:
+		return "redirect"
+	case *expr.Reject:
+		return "reject"
+	}
+	// e.g. *expr.Range -> range
+	typeName := fmt.Sprintf("%T", e)
+	return strings.ToLower(typeName[strings.LastIndex(typeName, ".")+1:])
+}
+
+func nftVerdictString(v *expr.Verdict) string {
+	switch v.Kind {
+	case expr.VerdictAccept:
+		return "accept"
+	case expr.VerdictDrop:
+		return "drop"
+	case expr.VerdictReturn:
+		return "return"
+	case expr.VerdictContinue:
+		return "continue"
+	case expr.VerdictQueue:
+		return "queue"
+	case expr.VerdictJump:
+		return "jump " + v.Chain
+	case expr.VerdictGoto:
+		return "goto " + v.Chain
+	}
+	return fmt.Sprintf("verdict %d", v.Kind)
+}
+
+// nftDataString returns printable data (like interface names) as a quoted string and other data as hex
+func nftDataString(data []byte) string {
+	str := strings.TrimRight(string(data), "\x00")
+	printable := str != ""
+	for _, r := range str {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			printable = false
+			break
+		}
+	}
+	if printable && len(data) > 1 {
+		return strconv.Quote(str)
+	}
+	return "0x" + hex.EncodeToString(data)
+}
@@ -0,0 +1,69 @@
+package sensor
+
+import (
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_decodeNFTRuleset(t *testing.T) {
+	table := &nftables.Table{Name: "filter", Family: nftables.TableFamilyINet}
+	policy := nftables.ChainPolicyDrop
+	input := &nftables.Chain{Name: "input", Table: &nftables.Table{Name: "filter", Family: nftables.TableFamilyINet},
+		Hooknum: nftables.ChainHookInput, Priority: nftables.ChainPriorityFilter, Type: nftables.ChainTypeFilter, Policy: &policy}
+	services := &nftables.Chain{Name: "services", Table: &nftables.Table{Name: "filter", Family: nftables.TableFamilyINet}}
+	orphan := &nftables.Chain{Name: "orphan", Table: &nftables.Table{Name: "nat", Family: nftables.TableFamilyIPv4}}
+
+	rules := map[*nftables.Chain][]*nftables.Rule{
+		input: {
+			{Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte("lo\x00")},
+				&expr.Verdict{Kind: expr.VerdictAccept},
+			}},
+			{Exprs: []expr.Any{
+				&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{6}},
+				&expr.Payload{Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2, DestRegister: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{0x28, 0x0a}},
+				&expr.Counter{},
+				&expr.Verdict{Kind: expr.VerdictJump, Chain: "services"},
+			}},
+		},
+		services: {
+			{Exprs: []expr.Any{
+				&expr.Match{Name: "comment"},
+				&expr.NAT{Type: expr.NATTypeDestNAT},
+			}},
+		},
+	}
+
+	res := decodeNFTRuleset([]*nftables.Table{table}, []*nftables.Chain{input, services, orphan}, rules)
+	assert.Equal(t, []FirewallTable{
+		{
+			Backend: firewallBackendNFTables,
+			Family:  "inet",
+			Name:    "filter",
+			Chains: []FirewallChain{
+				{
+					Name:   "input",
+					Hook:   "input",
+					Type:   "filter",
+					Policy: "drop",
+					Rules: []FirewallRule{
+						{Spec: `meta iifname == "lo" accept`, Target: "accept"},
+						{Spec: "meta l4proto == 0x06 payload transport+2/2 == 0x280a counter jump services", Target: "jump services"},
+					},
+				},
+				{
+					Name: "services",
+					Rules: []FirewallRule{
+						{Spec: "xt match comment dnat", Matches: []string{"comment"}, Target: "dnat"},
+					},
+				},
+			},
+		},
+	}, res)
+}
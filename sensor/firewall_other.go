@@ -0,0 +1,7 @@
+//go:build !linux
+
+package sensor
+
+func newHostFirewallReader() (firewallReader, error) {
+	return nil, ErrFirewallNotSupported
+}
@@ -0,0 +1,274 @@
+package sensor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// iptablesFixture builds the raw replies of IPT_SO_GET_INFO and IPT_SO_GET_ENTRIES
+type iptablesFixture struct {
+	family iptablesFamily
+	info   iptablesInfo
+	blob   []byte
+}
+
+type iptablesFixtureRule struct {
+	src      string
+	dst      string
+	invDst   bool
+	inIface  string
+	outIface string
+	proto    uint16
+	matches  []string
+	goTo     bool
+	// raw flags, e.g. IP6T_F_TOS
+	flags byte
+}
+
+func (f *iptablesFixture) add(rule iptablesFixtureRule, target []byte) int {
+	entry := make([]byte, f.family.entrySize)
+	addrLen := f.family.addrLen
+	for i, cidr := range []string{rule.src, rule.dst} {
+		if cidr == "" {
+			continue
+		}
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		copy(entry[i*addrLen:], ipNet.IP)
+		copy(entry[(2+i)*addrLen:], ipNet.Mask)
+	}
+	for i, iface := range []string{rule.inIface, rule.outIface} {
+		if iface == "" {
+			continue
+		}
+		copy(entry[4*addrLen+16*i:], iface)
+		maskLen := len(iface) + 1
+		if iface[len(iface)-1] == '+' {
+			entry[4*addrLen+16*i+len(iface)-1] = 0
+			maskLen = len(iface) - 1
+		}
+		for j := 0; j < maskLen; j++ {
+			entry[4*addrLen+32+16*i+j] = 0xff
+		}
+	}
+	binary.NativeEndian.PutUint16(entry[f.family.protoOffset:], rule.proto)
+	entry[f.family.invFlagsOffset-1] = rule.flags
+	if rule.goTo {
+		entry[f.family.invFlagsOffset-1] |= f.family.gotoFlag
+	}
+	if rule.invDst {
+		entry[f.family.invFlagsOffset] = iptInvDstIP
+	}
+	for _, name := range rule.matches {
+		entry = append(entry, xtEntry(name, nil)...)
+	}
+	binary.NativeEndian.PutUint16(entry[f.family.targetOffset:], uint16(len(entry)))
+	entry = append(entry, target...)
+	binary.NativeEndian.PutUint16(entry[f.family.targetOffset+2:], uint16(len(entry)))
+
+	offset := len(f.blob)
+	f.blob = append(f.blob, entry...)
+	return offset
+}
+
+// setVerdict sets the verdict of the standard target of the entry at `offset`
+func (f *iptablesFixture) setVerdict(offset int, verdict int) {
+	targetOffset := int(binary.NativeEndian.Uint16(f.blob[offset+f.family.targetOffset:]))
+	binary.NativeEndian.PutUint32(f.blob[offset+targetOffset+xtEntryHeaderSize:], uint32(int32(verdict)))
+}
+
+func (f *iptablesFixture) rawInfo() []byte {
+	b := make([]byte, iptGetInfoSize)
+	binary.NativeEndian.PutUint32(b[32:], f.info.ValidHooks)
+	for i := 0; i < iptNumberOfHooks; i++ {
+		binary.NativeEndian.PutUint32(b[36+4*i:], f.info.HookEntry[i])
+		binary.NativeEndian.PutUint32(b[56+4*i:], f.info.Underflow[i])
+	}
+	binary.NativeEndian.PutUint32(b[80:], uint32(len(f.blob)))
+	return b
+}
+
+// xtEntry returns a match or a target
+func xtEntry(name string, data []byte) []byte {
+	b := make([]byte, xtEntryHeaderSize, xtEntryHeaderSize+len(data)+8)
+	copy(b[2:], name)
+	b = append(b, data...)
+	// align to 8 bytes
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	binary.NativeEndian.PutUint16(b, uint16(len(b)))
+	return b
+}
+
+func standardTarget(verdict int32) []byte {
+	data := make([]byte, 4)
+	binary.NativeEndian.PutUint32(data, uint32(verdict))
+	return xtEntry(xtStandardTarget, data)
+}
+
+func errorTarget(name string) []byte {
+	data := make([]byte, 32)
+	copy(data, name)
+	return xtEntry(xtErrorTarget, data)
+}
+
+// newFilterTableFixture returns a filter table with INPUT, FORWARD, OUTPUT and a user defined chain
+func newFilterTableFixture(family iptablesFamily) *iptablesFixture {
+	f := &iptablesFixture{family: family}
+	f.info.ValidHooks = 1<<1 | 1<<2 | 1<<3
+
+	// INPUT
+	f.info.HookEntry[1] = uint32(f.add(iptablesFixtureRule{src: "10.0.0.0/8", inIface: "eth+", proto: 6, matches: []string{"conntrack"}},
+		standardTarget(iptVerdictAccept)))
+	f.info.Underflow[1] = uint32(f.add(iptablesFixtureRule{}, standardTarget(iptVerdictAccept)))
+	// FORWARD
+	jump := f.add(iptablesFixtureRule{}, standardTarget(0))
+	f.info.HookEntry[2] = uint32(jump)
+	f.info.Underflow[2] = uint32(f.add(iptablesFixtureRule{}, standardTarget(iptVerdictDrop)))
+	// OUTPUT
+	f.info.HookEntry[3] = uint32(f.add(iptablesFixtureRule{}, standardTarget(iptVerdictAccept)))
+	f.info.Underflow[3] = f.info.HookEntry[3]
+	// KUBE-FORWARD
+	f.add(iptablesFixtureRule{}, errorTarget("KUBE-FORWARD"))
+	f.setVerdict(jump, f.add(iptablesFixtureRule{dst: "10.96.0.0/12", invDst: true, outIface: "cni0", matches: []string{"comment"}},
+		xtEntry("MARK", make([]byte, 8))))
+	fallThrough := f.add(iptablesFixtureRule{proto: 17}, standardTarget(0))
+	f.setVerdict(fallThrough, len(f.blob))
+	f.add(iptablesFixtureRule{}, standardTarget(iptVerdictReturn))
+	// end of table
+	f.add(iptablesFixtureRule{}, errorTarget(xtErrorTarget))
+
+	return f
+}
+
+func Test_decodeIPTablesEntries(t *testing.T) {
+	f := newFilterTableFixture(iptablesFamilyIPv4)
+	info, err := decodeIPTablesInfo(f.rawInfo())
+	require.NoError(t, err)
+
+	chains, err := decodeIPTablesEntries(iptablesFamilyIPv4, info, f.blob)
+	require.NoError(t, err)
+	assert.Equal(t, []FirewallChain{
+		{
+			Name:   "INPUT",
+			Hook:   "INPUT",
+			Policy: "ACCEPT",
+			Rules: []FirewallRule{
+				{Spec: "-s 10.0.0.0/8 -i eth+ -p tcp -m conntrack -j ACCEPT", Matches: []string{"conntrack"}, Target: "ACCEPT"},
+			},
+		},
+		{
+			Name:   "FORWARD",
+			Hook:   "FORWARD",
+			Policy: "DROP",
+			Rules: []FirewallRule{
+				{Spec: "-j KUBE-FORWARD", Target: "KUBE-FORWARD"},
+			},
+		},
+		{
+			Name:   "OUTPUT",
+			Hook:   "OUTPUT",
+			Policy: "ACCEPT",
+			Rules:  []FirewallRule{},
+		},
+		{
+			Name: "KUBE-FORWARD",
+			Rules: []FirewallRule{
+				{Spec: "! -d 10.96.0.0/12 -o cni0 -m comment -j MARK", Matches: []string{"comment"}, Target: "MARK"},
+				{Spec: "-p udp"},
+			},
+		},
+	}, chains)
+}
+
+func Test_decodeIPTablesEntriesIPv6(t *testing.T) {
+	f := &iptablesFixture{family: iptablesFamilyIPv6}
+	f.info.ValidHooks = 1 << 1
+	f.info.HookEntry[1] = uint32(f.add(iptablesFixtureRule{src: "2001:db8::/32", proto: 58, goTo: true}, standardTarget(0)))
+	f.setVerdict(int(f.info.HookEntry[1]), int(f.info.HookEntry[1]))
+	// IP6T_F_TOS is the goto flag of ipv4
+	f.add(iptablesFixtureRule{proto: 6, flags: 0x02}, standardTarget(iptVerdictAccept))
+	f.info.Underflow[1] = uint32(f.add(iptablesFixtureRule{}, standardTarget(iptVerdictDrop)))
+	f.add(iptablesFixtureRule{}, errorTarget(xtErrorTarget))
+
+	info, err := decodeIPTablesInfo(f.rawInfo())
+	require.NoError(t, err)
+	chains, err := decodeIPTablesEntries(iptablesFamilyIPv6, info, f.blob)
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, "DROP", chains[0].Policy)
+	assert.Equal(t, []FirewallRule{
+		{Spec: "-s 2001:db8::/32 -p ipv6-icmp -g INPUT", Target: "INPUT"},
+		{Spec: "-p tcp -j ACCEPT", Target: "ACCEPT"},
+	}, chains[0].Rules)
+}
+
+func Test_decodeIPTablesEntriesInvalid(t *testing.T) {
+	f := newFilterTableFixture(iptablesFamilyIPv4)
+	info, err := decodeIPTablesInfo(f.rawInfo())
+	require.NoError(t, err)
+
+	_, err = decodeIPTablesEntries(iptablesFamilyIPv4, info, f.blob[:len(f.blob)-10])
+	assert.Error(t, err)
+
+	_, err = decodeIPTablesInfo(f.rawInfo()[:10])
+	assert.Error(t, err)
+}
+
+// fixtureFirewallReader returns fixed rulesets
+type fixtureFirewallReader struct {
+	nft      []FirewallTable
+	iptables map[string]*iptablesFixture
+}
+
+func (r *fixtureFirewallReader) nfTables() ([]FirewallTable, error) {
+	if r.nft == nil {
+		return nil, errors.New("nf_tables not loaded")
+	}
+	return r.nft, nil
+}
+
+func (r *fixtureFirewallReader) iptablesTable(family iptablesFamily, table string) ([]byte, []byte, error) {
+	f, ok := r.iptables[family.name+"/"+table]
+	if !ok {
+		return nil, nil, errors.New("no such table")
+	}
+	return f.rawInfo(), f.blob, nil
+}
+
+func (r *fixtureFirewallReader) close() {}
+
+func Test_senseFirewallRules(t *testing.T) {
+	nft := []FirewallTable{
+		{Backend: firewallBackendNFTables, Family: "inet", Name: "kube-proxy", Chains: []FirewallChain{}},
+	}
+	reader := &fixtureFirewallReader{
+		nft: nft,
+		iptables: map[string]*iptablesFixture{
+			"ip/filter":  newFilterTableFixture(iptablesFamilyIPv4),
+			"ip6/filter": newFilterTableFixture(iptablesFamilyIPv6),
+		},
+	}
+
+	res := senseFirewallRules(context.TODO(), reader)
+	require.Len(t, res.Tables, 3)
+	assert.Equal(t, nft[0], res.Tables[0])
+	for i, family := range []string{"ip", "ip6"} {
+		table := res.Tables[i+1]
+		assert.Equal(t, firewallBackendIPTablesLegacy, table.Backend)
+		assert.Equal(t, family, table.Family)
+		assert.Equal(t, "filter", table.Name)
+		assert.Len(t, table.Chains, 4)
+	}
+
+	// nftables is not available
+	reader.nft = nil
+	res = senseFirewallRules(context.TODO(), reader)
+	assert.Len(t, res.Tables, 2)
+}
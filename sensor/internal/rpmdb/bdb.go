@@ -0,0 +1,168 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// A minimal read-only reader of Berkeley DB hash databases, enough to read the values of /var/lib/rpm/Packages.
+// See db_page.h of Berkeley DB for the on-disk structures.
+
+const (
+	bdbHashMagic = 0x061561
+
+	bdbPageHeaderSize = 26
+
+	bdbPageOverflow     = 7
+	bdbPageHashUnsorted = 2
+	bdbPageHash         = 13
+	bdbPageHashMeta     = 8
+
+	// hash item types
+	bdbItemKeyData = 1
+	bdbItemOffPage = 3
+)
+
+type bdbDB struct {
+	reader    io.ReaderAt
+	byteOrder binary.ByteOrder
+	pageSize  uint32
+	lastPgno  uint32
+}
+
+func newBDB(reader io.ReaderAt) (*bdbDB, error) {
+	meta := make([]byte, 72)
+	if _, err := reader.ReadAt(meta, 0); err != nil {
+		return nil, fmt.Errorf("failed to read metadata page: %w", err)
+	}
+	// the database is written in the byte order of the host which created it
+	var byteOrder binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(meta[12:16]) == bdbHashMagic:
+		byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(meta[12:16]) == bdbHashMagic:
+		byteOrder = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a Berkeley DB hash database")
+	}
+	if meta[25] != bdbPageHashMeta {
+		return nil, fmt.Errorf("unexpected metadata page type %d", meta[25])
+	}
+	if meta[24] != 0 {
+		return nil, fmt.Errorf("encrypted databases are not supported")
+	}
+	db := bdbDB{
+		reader:    reader,
+		byteOrder: byteOrder,
+		pageSize:  byteOrder.Uint32(meta[20:24]),
+		lastPgno:  byteOrder.Uint32(meta[32:36]),
+	}
+	if db.pageSize < 512 || db.pageSize > 65536 || db.pageSize&(db.pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size %d", db.pageSize)
+	}
+	return &db, nil
+}
+
+func isBDB(magic []byte) bool {
+	return len(magic) >= 16 &&
+		(binary.LittleEndian.Uint32(magic[12:16]) == bdbHashMagic || binary.BigEndian.Uint32(magic[12:16]) == bdbHashMagic)
+}
+
+// page reads page number `pgno` (0 based)
+func (db *bdbDB) page(pgno uint32) ([]byte, error) {
+	if pgno > db.lastPgno {
+		return nil, fmt.Errorf("page %d is out of range", pgno)
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.reader.ReadAt(page, int64(pgno)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", pgno, err)
+	}
+	return page, nil
+}
+
+// values returns all the values (not the keys) stored in the hash pages
+func (db *bdbDB) values() ([][]byte, error) {
+	var values [][]byte
+	for pgno := uint32(1); pgno <= db.lastPgno; pgno++ {
+		page, err := db.page(pgno)
+		if err != nil {
+			return nil, err
+		}
+		if pageType := page[25]; pageType != bdbPageHash && pageType != bdbPageHashUnsorted {
+			continue
+		}
+
+		entries := int(db.byteOrder.Uint16(page[20:22]))
+		if bdbPageHeaderSize+entries*2 > len(page) {
+			return nil, fmt.Errorf("page %d: invalid entry count", pgno)
+		}
+		// the items are stored from the end of the page, as key/value pairs
+		itemEnd := len(page)
+		var key []byte
+		for i := 0; i < entries; i++ {
+			itemStart := int(db.byteOrder.Uint16(page[bdbPageHeaderSize+i*2:]))
+			if itemStart >= itemEnd {
+				return nil, fmt.Errorf("page %d: invalid item offset", pgno)
+			}
+			item := page[itemStart:itemEnd]
+			itemEnd = itemStart
+			if i%2 == 0 {
+				key = item
+				continue
+			}
+			// rpm stores the largest header instance number under the key 0, see pkgInstance of lib/rpmdb.c
+			if isBDBZeroKey(key) {
+				continue
+			}
+
+			switch item[0] {
+			case bdbItemKeyData:
+				values = append(values, item[1:])
+			case bdbItemOffPage:
+				if len(item) < 12 {
+					return nil, fmt.Errorf("page %d: truncated off-page item", pgno)
+				}
+				value, err := db.overflowValue(db.byteOrder.Uint32(item[4:8]), db.byteOrder.Uint32(item[8:12]))
+				if err != nil {
+					return nil, fmt.Errorf("page %d: %w", pgno, err)
+				}
+				values = append(values, value)
+			}
+		}
+	}
+	return values, nil
+}
+
+// isBDBZeroKey returns true for an inline key holding the uint32 0
+func isBDBZeroKey(key []byte) bool {
+	return len(key) == 5 && key[0] == bdbItemKeyData && binary.LittleEndian.Uint32(key[1:]) == 0
+}
+
+// overflowValue reads a value of `length` bytes stored in the overflow pages chain starting at `pgno`
+func (db *bdbDB) overflowValue(pgno, length uint32) ([]byte, error) {
+	if length > headerMaxDataSize*2 {
+		return nil, fmt.Errorf("overflow value is too large (%d bytes)", length)
+	}
+	value := make([]byte, 0, length)
+	for pages := uint32(0); uint32(len(value)) < length; pages++ {
+		if pgno == 0 || pages > db.lastPgno {
+			return nil, fmt.Errorf("overflow chain is truncated")
+		}
+		page, err := db.page(pgno)
+		if err != nil {
+			return nil, err
+		}
+		if page[25] != bdbPageOverflow {
+			return nil, fmt.Errorf("page %d: unexpected overflow page type %d", pgno, page[25])
+		}
+		// the length of the data in an overflow page is stored in hf_offset
+		dataLength := int(db.byteOrder.Uint16(page[22:24]))
+		if bdbPageHeaderSize+dataLength > len(page) {
+			return nil, fmt.Errorf("page %d: invalid overflow data length", pgno)
+		}
+		value = append(value, page[bdbPageHeaderSize:bdbPageHeaderSize+dataLength]...)
+		pgno = db.byteOrder.Uint32(page[16:20])
+	}
+	return value[:length], nil
+}
@@ -0,0 +1,136 @@
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// rpm header tags, see rpmtag.h
+const (
+	tagName      = 1000
+	tagVersion   = 1001
+	tagRelease   = 1002
+	tagEpoch     = 1003
+	tagSummary   = 1004
+	tagSize      = 1009
+	tagVendor    = 1011
+	tagLicense   = 1014
+	tagPackager  = 1015
+	tagArch      = 1022
+	tagSourceRPM = 1044
+)
+
+// rpm header data types
+const (
+	typeInt32       = 4
+	typeString      = 6
+	typeStringArray = 8
+	typeI18NString  = 9
+)
+
+const (
+	headerIndexEntrySize = 16
+	// rpm refuses headers with more than 16MB of data, so does this parser
+	headerMaxDataSize = 16 * 1024 * 1024
+)
+
+// Package holds the fields of an installed rpm package header
+type Package struct {
+	Name    string
+	Version string
+	Release string
+	Epoch   *int
+	Arch    string
+	Summary string
+	Vendor  string
+	License string
+	// Packager, e.g. "Red Hat, Inc. <http://bugzilla.redhat.com/bugzilla>"
+	Packager string
+	// The name of the source rpm, e.g. "bash-5.1.8-6.el9.src.rpm"
+	SourceRPM string
+	Size      int
+}
+
+// parseHeader parses an rpm header blob as stored in the rpm database,
+// i.e. the index entry count and data length followed by the index entries and the data store.
+func parseHeader(blob []byte) (*Package, error) {
+	if len(blob) < 8 {
+		return nil, fmt.Errorf("header blob is too short (%d bytes)", len(blob))
+	}
+	indexCount := int(binary.BigEndian.Uint32(blob[0:4]))
+	dataLength := int(binary.BigEndian.Uint32(blob[4:8]))
+	if dataLength > headerMaxDataSize || indexCount > headerMaxDataSize/headerIndexEntrySize {
+		return nil, fmt.Errorf("header is too large (%d entries, %d bytes)", indexCount, dataLength)
+	}
+	dataStart := 8 + indexCount*headerIndexEntrySize
+	if len(blob) < dataStart+dataLength {
+		return nil, fmt.Errorf("header blob is truncated (%d bytes, expected %d)", len(blob), dataStart+dataLength)
+	}
+	data := blob[dataStart : dataStart+dataLength]
+
+	pkg := Package{}
+	for i := 0; i < indexCount; i++ {
+		entry := blob[8+i*headerIndexEntrySize:]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		dataType := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(int32(binary.BigEndian.Uint32(entry[8:12])))
+		count := int(binary.BigEndian.Uint32(entry[12:16]))
+		if offset < 0 || offset >= len(data) {
+			continue
+		}
+
+		switch tag {
+		case tagName:
+			pkg.Name = headerString(data, offset, dataType)
+		case tagVersion:
+			pkg.Version = headerString(data, offset, dataType)
+		case tagRelease:
+			pkg.Release = headerString(data, offset, dataType)
+		case tagEpoch:
+			if epoch, ok := headerInt32(data, offset, dataType, count); ok {
+				pkg.Epoch = &epoch
+			}
+		case tagSummary:
+			pkg.Summary = headerString(data, offset, dataType)
+		case tagSize:
+			if size, ok := headerInt32(data, offset, dataType, count); ok {
+				pkg.Size = size
+			}
+		case tagVendor:
+			pkg.Vendor = headerString(data, offset, dataType)
+		case tagLicense:
+			pkg.License = headerString(data, offset, dataType)
+		case tagPackager:
+			pkg.Packager = headerString(data, offset, dataType)
+		case tagArch:
+			pkg.Arch = headerString(data, offset, dataType)
+		case tagSourceRPM:
+			pkg.SourceRPM = headerString(data, offset, dataType)
+		}
+	}
+
+	if pkg.Name == "" {
+		return nil, fmt.Errorf("header has no package name")
+	}
+	return &pkg, nil
+}
+
+// headerString returns the (first) NUL terminated string at `offset`
+func headerString(data []byte, offset int, dataType uint32) string {
+	switch dataType {
+	case typeString, typeStringArray, typeI18NString:
+	default:
+		return ""
+	}
+	value, _, _ := bytes.Cut(data[offset:], []byte{0})
+	return string(value)
+}
+
+// headerInt32 returns the first int32 value at `offset`
+func headerInt32(data []byte, offset int, dataType uint32, count int) (int, bool) {
+	if dataType != typeInt32 || count < 1 || offset+4 > len(data) {
+		return 0, false
+	}
+	return int(int32(binary.BigEndian.Uint32(data[offset : offset+4]))), true
+}
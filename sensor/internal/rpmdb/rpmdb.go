@@ -0,0 +1,66 @@
+// Package rpmdb reads the installed packages of an rpm database, without depending on rpm or its database libraries.
+// The SQLite (rpmdb.sqlite) and the Berkeley DB (Packages) formats are supported.
+package rpmdb
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadPackages reads the packages of the rpm database `fileName`, detecting its format
+func ReadPackages(fileName string) ([]Package, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 16)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+
+	var blobs [][]byte
+	switch {
+	case isSQLite(magic):
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		// the committed transactions which are not checkpointed into the database yet
+		wal, err := os.ReadFile(fileName + "-wal")
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s-wal: %w", fileName, err)
+		}
+		blobs, err = readSQLitePackages(data, wal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+	case isBDB(magic):
+		db, err := newBDB(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+		blobs, err = db.values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rpm database format of %s", fileName)
+	}
+
+	packages := make([]Package, 0, len(blobs))
+	for _, blob := range blobs {
+		pkg, err := parseHeader(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse package header of %s: %w", fileName, err)
+		}
+		// the gpg-pubkey pseudo packages hold the imported signing keys
+		if pkg.Name == "gpg-pubkey" {
+			continue
+		}
+		packages = append(packages, *pkg)
+	}
+	return packages, nil
+}
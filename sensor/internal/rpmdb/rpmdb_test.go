@@ -0,0 +1,133 @@
+package rpmdb
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReadPackagesSQLite(t *testing.T) {
+	packages, err := ReadPackages("testdata/rpmdb.sqlite")
+	require.NoError(t, err)
+
+	// the gpg-pubkey pseudo package is skipped
+	require.Len(t, packages, 42)
+	assert.Equal(t, Package{
+		Name:      "bash",
+		Version:   "5.1.8",
+		Release:   "6.el9",
+		Arch:      "x86_64",
+		Summary:   "bash package",
+		Vendor:    "Red Hat, Inc.",
+		License:   "GPLv3+",
+		SourceRPM: "bash-5.1.8-6.el9.src.rpm",
+		Size:      2500,
+	}, packages[0])
+
+	require.NotNil(t, packages[1].Epoch)
+	assert.Equal(t, 1, *packages[1].Epoch)
+	assert.Equal(t, "openssl-libs", packages[1].Name)
+	assert.Equal(t, "pkg39", packages[41].Name)
+}
+
+func Test_ReadPackagesSQLiteWAL(t *testing.T) {
+	names := func(packages []Package) []string {
+		res := []string{}
+		for _, pkg := range packages {
+			res = append(res, pkg.Name+"-"+pkg.Release)
+		}
+		return res
+	}
+
+	// zlib is installed, and openssl-libs is updated, in transactions which are only in the WAL
+	packages, err := ReadPackages("testdata/wal/rpmdb.sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bash-6.el9", "openssl-libs-28.el9", "zlib-40.el9"}, names(packages))
+
+	data, err := os.ReadFile("testdata/wal/rpmdb.sqlite")
+	require.NoError(t, err)
+	wal, err := os.ReadFile("testdata/wal/rpmdb.sqlite-wal")
+	require.NoError(t, err)
+
+	// without the WAL
+	blobs, err := readSQLitePackages(data, nil)
+	require.NoError(t, err)
+	assert.Len(t, blobs, 2)
+
+	// a frame which doesn't match the salt of the WAL header is ignored
+	frame := append([]byte{}, wal[sqliteWALHeaderSize:sqliteWALHeaderSize+sqliteWALFrameHeaderSize+1024]...)
+	frame[8]++
+	blobs, err = readSQLitePackages(data, append(append([]byte{}, wal...), frame...))
+	require.NoError(t, err)
+	assert.Len(t, blobs, 3)
+
+	// the transactions of frames with an invalid checksum, and of the frames which follow them, are ignored
+	corrupted := append([]byte{}, wal...)
+	corrupted[len(corrupted)-1]++
+	blobs, err = readSQLitePackages(data, corrupted)
+	require.NoError(t, err)
+	packages = nil
+	for _, blob := range blobs {
+		pkg, err := parseHeader(blob)
+		require.NoError(t, err)
+		packages = append(packages, *pkg)
+	}
+	assert.Equal(t, []string{"bash-6.el9", "openssl-libs-27.el9", "zlib-40.el9"}, names(packages))
+
+	_, err = readSQLitePackages(data, []byte("not a write-ahead log, long enough for a header"))
+	assert.ErrorContains(t, err, "invalid WAL header")
+}
+
+func Test_ReadPackagesBDB(t *testing.T) {
+	packages, err := ReadPackages("testdata/Packages")
+	require.NoError(t, err)
+
+	require.Len(t, packages, 2)
+	// an inline value
+	assert.Equal(t, "zlib", packages[0].Name)
+	assert.Equal(t, "1.2.11", packages[0].Version)
+	assert.Nil(t, packages[0].Epoch)
+	// a value in overflow pages, the key 0 record of rpm is skipped
+	assert.Equal(t, "bash", packages[1].Name)
+	assert.Equal(t, "bash-5.1.8-6.el9.src.rpm", packages[1].SourceRPM)
+}
+
+func Test_ReadPackagesErrors(t *testing.T) {
+	_, err := ReadPackages("testdata/notadb")
+	assert.ErrorContains(t, err, "unsupported rpm database format")
+
+	_, err = ReadPackages("testdata/missing")
+	assert.Error(t, err)
+}
+
+func Test_parseHeader(t *testing.T) {
+	_, err := parseHeader([]byte{0, 0})
+	assert.ErrorContains(t, err, "too short")
+
+	// one entry but no data
+	_, err = parseHeader([]byte{0, 0, 0, 1, 0, 0, 0, 0})
+	assert.ErrorContains(t, err, "truncated")
+
+	_, err = parseHeader([]byte{0, 0, 0, 0, 0, 0, 0, 0})
+	assert.ErrorContains(t, err, "no package name")
+}
+
+func Test_readVarint(t *testing.T) {
+	tests := []struct {
+		buf   []byte
+		value uint64
+		n     int
+	}{
+		{[]byte{0x05}, 5, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0xffffffffffffffff, 9},
+		{[]byte{0x81}, 0, 0},
+	}
+	for _, tt := range tests {
+		value, n := readVarint(tt.buf)
+		assert.Equal(t, tt.value, value)
+		assert.Equal(t, tt.n, n)
+	}
+}
@@ -0,0 +1,390 @@
+package rpmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// A minimal read-only reader of SQLite table b-trees, enough to read the `Packages` table of rpmdb.sqlite.
+// The transactions committed in the write-ahead log (rpmdb.sqlite-wal) which are not checkpointed yet are applied.
+// See https://www.sqlite.org/fileformat.html
+
+const (
+	sqliteMagic          = "SQLite format 3\x00"
+	sqliteHeaderSize     = 100
+	sqliteSchemaRootPage = 1
+	sqliteMaxDepth       = 64
+
+	sqlitePageInteriorTable = 0x05
+	sqlitePageLeafTable     = 0x0d
+
+	sqliteWALMagic           = 0x377f0682
+	sqliteWALHeaderSize      = 32
+	sqliteWALFrameHeaderSize = 24
+
+	// CREATE TABLE 'Packages' (hnum INTEGER PRIMARY KEY AUTOINCREMENT, blob BLOB NOT NULL)
+	rpmPackagesTable      = "Packages"
+	rpmPackagesBlobColumn = 1
+)
+
+type sqliteDB struct {
+	data []byte
+	// page size and usable size (page size without the reserved space)
+	pageSize   int
+	usableSize int
+}
+
+func isSQLite(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sqliteMagic))
+}
+
+func newSQLiteDB(data []byte) (*sqliteDB, error) {
+	if !isSQLite(data) || len(data) < sqliteHeaderSize {
+		return nil, fmt.Errorf("not an SQLite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid SQLite page size %d", pageSize)
+	}
+	return &sqliteDB{data: data, pageSize: pageSize, usableSize: pageSize - int(data[20])}, nil
+}
+
+// page returns the content of page number `pgno` (1 based)
+func (db *sqliteDB) page(pgno uint32) ([]byte, error) {
+	start := int(pgno-1) * db.pageSize
+	if pgno == 0 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("page %d is out of range", pgno)
+	}
+	return db.data[start : start+db.pageSize], nil
+}
+
+// tableRootPage looks up the root page of `tableName` in the sqlite_schema table
+func (db *sqliteDB) tableRootPage(tableName string) (uint32, error) {
+	var rootPage uint32
+	err := db.walkTable(sqliteSchemaRootPage, func(record []interface{}) error {
+		// type, name, tbl_name, rootpage, sql
+		if len(record) < 4 || record[0] != "table" || record[1] != tableName {
+			return nil
+		}
+		if pgno, ok := record[3].(int64); ok {
+			rootPage = uint32(pgno)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if rootPage == 0 {
+		return 0, fmt.Errorf("table %q not found", tableName)
+	}
+	return rootPage, nil
+}
+
+// walkTable calls `fn` with the columns of each row of the table b-tree at `rootPage`
+func (db *sqliteDB) walkTable(rootPage uint32, fn func(record []interface{}) error) error {
+	return db.walkTablePage(rootPage, 0, fn)
+}
+
+func (db *sqliteDB) walkTablePage(pgno uint32, depth int, fn func(record []interface{}) error) error {
+	if depth > sqliteMaxDepth {
+		return fmt.Errorf("b-tree is too deep")
+	}
+	page, err := db.page(pgno)
+	if err != nil {
+		return err
+	}
+	// the first page starts with the database header
+	headerOffset := 0
+	if pgno == 1 {
+		headerOffset = sqliteHeaderSize
+	}
+	header := page[headerOffset:]
+	cellCount := int(binary.BigEndian.Uint16(header[3:5]))
+
+	switch header[0] {
+	case sqlitePageInteriorTable:
+		cellPointers := header[12:]
+		if len(cellPointers) < cellCount*2 {
+			return fmt.Errorf("page %d: invalid cell count", pgno)
+		}
+		for i := 0; i < cellCount; i++ {
+			cellOffset := int(binary.BigEndian.Uint16(cellPointers[i*2:]))
+			if cellOffset+4 > len(page) {
+				return fmt.Errorf("page %d: invalid cell offset", pgno)
+			}
+			if err := db.walkTablePage(binary.BigEndian.Uint32(page[cellOffset:]), depth+1, fn); err != nil {
+				return err
+			}
+		}
+		return db.walkTablePage(binary.BigEndian.Uint32(header[8:12]), depth+1, fn)
+
+	case sqlitePageLeafTable:
+		cellPointers := header[8:]
+		if len(cellPointers) < cellCount*2 {
+			return fmt.Errorf("page %d: invalid cell count", pgno)
+		}
+		for i := 0; i < cellCount; i++ {
+			cellOffset := int(binary.BigEndian.Uint16(cellPointers[i*2:]))
+			if cellOffset >= len(page) {
+				return fmt.Errorf("page %d: invalid cell offset", pgno)
+			}
+			payload, err := db.cellPayload(page[cellOffset:])
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pgno, err)
+			}
+			record, err := parseRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pgno, err)
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("page %d: unexpected page type 0x%02x", pgno, header[0])
+	}
+}
+
+// cellPayload returns the payload of a table leaf cell, following its overflow pages
+func (db *sqliteDB) cellPayload(cell []byte) ([]byte, error) {
+	payloadSize, n := readVarint(cell)
+	if n == 0 {
+		return nil, fmt.Errorf("invalid cell")
+	}
+	// skip the rowid
+	_, m := readVarint(cell[n:])
+	if m == 0 {
+		return nil, fmt.Errorf("invalid cell")
+	}
+	cell = cell[n+m:]
+	if payloadSize > uint64(len(db.data)) {
+		return nil, fmt.Errorf("invalid payload size %d", payloadSize)
+	}
+
+	size := int(payloadSize)
+	maxLocal := db.usableSize - 35
+	if size <= maxLocal {
+		if size > len(cell) {
+			return nil, fmt.Errorf("truncated cell")
+		}
+		return cell[:size], nil
+	}
+
+	minLocal := (db.usableSize-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usableSize-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if local+4 > len(cell) {
+		return nil, fmt.Errorf("truncated cell")
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+	overflow := binary.BigEndian.Uint32(cell[local:])
+	for len(payload) < size {
+		page, err := db.page(overflow)
+		if err != nil {
+			return nil, fmt.Errorf("overflow: %w", err)
+		}
+		overflow = binary.BigEndian.Uint32(page[0:4])
+		chunk := page[4:db.usableSize]
+		if remaining := size - len(payload); remaining < len(chunk) {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+		if overflow == 0 && len(payload) < size {
+			return nil, fmt.Errorf("overflow chain is truncated")
+		}
+	}
+	return payload, nil
+}
+
+// parseRecord decodes a record into its column values: nil, int64, uint64 (the bits of a float), string or []byte
+func parseRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := readVarint(payload)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("invalid record header")
+	}
+	serialTypes := payload[n:headerSize]
+	body := payload[headerSize:]
+
+	var record []interface{}
+	for len(serialTypes) > 0 {
+		serialType, n := readVarint(serialTypes)
+		if n == 0 {
+			return nil, fmt.Errorf("invalid record header")
+		}
+		serialTypes = serialTypes[n:]
+
+		var size int
+		switch {
+		case serialType == 0, serialType == 8, serialType == 9:
+			size = 0
+		case serialType <= 4:
+			size = int(serialType)
+		case serialType == 5:
+			size = 6
+		case serialType == 6, serialType == 7:
+			size = 8
+		case serialType >= 12:
+			size = int((serialType - 12) / 2)
+		default:
+			return nil, fmt.Errorf("invalid serial type %d", serialType)
+		}
+		if size > len(body) {
+			return nil, fmt.Errorf("truncated record")
+		}
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			record = append(record, nil)
+		case serialType == 8:
+			record = append(record, int64(0))
+		case serialType == 9:
+			record = append(record, int64(1))
+		case serialType == 7:
+			record = append(record, binary.BigEndian.Uint64(value))
+		case serialType <= 6:
+			// big-endian two's complement integer
+			var integer int64
+			if value[0]&0x80 != 0 {
+				integer = -1
+			}
+			for _, b := range value {
+				integer = integer<<8 | int64(b)
+			}
+			record = append(record, integer)
+		case serialType%2 == 0:
+			record = append(record, value)
+		default:
+			record = append(record, string(value))
+		}
+	}
+	return record, nil
+}
+
+// readVarint reads an SQLite variable length integer, returning the value and the number of bytes read (0 on error)
+func readVarint(buf []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(buf) {
+			return 0, 0
+		}
+		if i == 8 {
+			return value<<8 | uint64(buf[i]), 9
+		}
+		value = value<<7 | uint64(buf[i]&0x7f)
+		if buf[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// walChecksum continues the checksum `s0`, `s1` of the WAL over `b`, see "Checksum Algorithm" of the file format
+func walChecksum(byteOrder binary.ByteOrder, b []byte, s0, s1 uint32) (uint32, uint32) {
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += byteOrder.Uint32(b[i:]) + s1
+		s1 += byteOrder.Uint32(b[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// applyWAL returns the database with the committed frames of the write-ahead log `wal` applied, as SQLite reads it.
+// The frames are valid as long as their salts match the WAL header and their checksums chain; the frames which
+// follow the last commit frame belong to a transaction which is not committed.
+func (db *sqliteDB) applyWAL(wal []byte) (*sqliteDB, error) {
+	if len(wal) < sqliteWALHeaderSize {
+		return db, nil
+	}
+	magic := binary.BigEndian.Uint32(wal[0:4])
+	if magic&^1 != sqliteWALMagic {
+		return nil, fmt.Errorf("invalid WAL header")
+	}
+	// the lowest bit of the magic selects the byte order of the checksums
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if magic&1 != 0 {
+		byteOrder = binary.BigEndian
+	}
+	if pageSize := int(binary.BigEndian.Uint32(wal[8:12])); pageSize != db.pageSize {
+		return nil, fmt.Errorf("WAL page size %d doesn't match the database page size %d", pageSize, db.pageSize)
+	}
+	s0, s1 := walChecksum(byteOrder, wal[:24], 0, 0)
+	if s0 != binary.BigEndian.Uint32(wal[24:28]) || s1 != binary.BigEndian.Uint32(wal[28:32]) {
+		// a WAL with an invalid header is ignored
+		return db, nil
+	}
+	salt := wal[16:24]
+
+	committed := map[uint32][]byte{}
+	pending := map[uint32][]byte{}
+	dbPages := 0
+	frameSize := sqliteWALFrameHeaderSize + db.pageSize
+	for offset := sqliteWALHeaderSize; offset+frameSize <= len(wal); offset += frameSize {
+		frameHeader := wal[offset : offset+sqliteWALFrameHeaderSize]
+		page := wal[offset+sqliteWALFrameHeaderSize : offset+frameSize]
+		if !bytes.Equal(frameHeader[8:16], salt) {
+			break
+		}
+		s0, s1 = walChecksum(byteOrder, frameHeader[:8], s0, s1)
+		s0, s1 = walChecksum(byteOrder, page, s0, s1)
+		if s0 != binary.BigEndian.Uint32(frameHeader[16:20]) || s1 != binary.BigEndian.Uint32(frameHeader[20:24]) {
+			break
+		}
+		pending[binary.BigEndian.Uint32(frameHeader[0:4])] = page
+		// a commit frame holds the size of the database in pages after the commit
+		if commitSize := binary.BigEndian.Uint32(frameHeader[4:8]); commitSize != 0 {
+			for pgno, page := range pending {
+				committed[pgno] = page
+			}
+			pending = map[uint32][]byte{}
+			dbPages = int(commitSize)
+		}
+	}
+	if dbPages == 0 {
+		return db, nil
+	}
+
+	data := make([]byte, dbPages*db.pageSize)
+	copy(data, db.data)
+	for pgno, page := range committed {
+		if pgno != 0 && int(pgno) <= dbPages {
+			copy(data[int(pgno-1)*db.pageSize:], page)
+		}
+	}
+	return &sqliteDB{data: data, pageSize: db.pageSize, usableSize: db.usableSize}, nil
+}
+
+// readSQLitePackages reads the header blobs of the `Packages` table of `data`, with its write-ahead log `wal` (may be empty)
+func readSQLitePackages(data, wal []byte) ([][]byte, error) {
+	db, err := newSQLiteDB(data)
+	if err != nil {
+		return nil, err
+	}
+	if db, err = db.applyWAL(wal); err != nil {
+		return nil, err
+	}
+	rootPage, err := db.tableRootPage(rpmPackagesTable)
+	if err != nil {
+		return nil, err
+	}
+	var blobs [][]byte
+	err = db.walkTable(rootPage, func(record []interface{}) error {
+		if len(record) <= rpmPackagesBlobColumn {
+			return nil
+		}
+		if blob, ok := record[rpmPackagesBlobColumn].([]byte); ok {
+			blobs = append(blobs, blob)
+		}
+		return nil
+	})
+	return blobs, err
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// RunInNetNs runs `fn` on a thread which joined the network namespace at `nsPath` (e.g. /proc/1/ns/net).
+// Network related procfs files and sockets opened by `fn` belong to that namespace.
+func RunInNetNs(nsPath string, fn func() error) error {
+	targetNetNs, err := os.Open(nsPath)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace: %w", err)
+	}
+	defer targetNetNs.Close()
+
+	runtime.LockOSThread()
+	restored := false
+	defer func() {
+		// a thread which failed to return to its namespace must not be reused
+		if restored {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	origNetNs, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		restored = true
+		return fmt.Errorf("failed to open current network namespace: %w", err)
+	}
+	defer origNetNs.Close()
+
+	if err := unix.Setns(int(targetNetNs.Fd()), unix.CLONE_NEWNET); err != nil {
+		restored = true
+		return fmt.Errorf("failed to join network namespace: %w", err)
+	}
+	fnErr := fn()
+	if err := unix.Setns(int(origNetNs.Fd()), unix.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("failed to restore network namespace: %w", err)
+	}
+	restored = true
+
+	return fnErr
+}
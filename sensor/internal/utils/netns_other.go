@@ -0,0 +1,14 @@
+//go:build !linux
+
+package utils
+
+import "errors"
+
+var (
+	ErrNetNsNotSupported = errors.New("network namespaces are not supported on this OS")
+)
+
+// RunInNetNs is not supported outside of linux
+func RunInNetNs(nsPath string, fn func() error) error {
+	return ErrNetNsNotSupported
+}
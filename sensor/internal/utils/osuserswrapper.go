@@ -290,3 +290,196 @@ func lookupUsernameByUID(uid int64, root string) (string, error) {
 
 	return userData.Username, nil
 }
+
+const shadowFile = "/etc/shadow"
+
+// Password states of a shadow entry
+const (
+	PasswordStatusSet    = "set"
+	PasswordStatusEmpty  = "empty"
+	PasswordStatusLocked = "locked"
+)
+
+// PasswdEntry is a row of a users file /etc/passwd
+type PasswdEntry struct {
+	Username string
+	UID      int64
+	GID      int64
+	Gecos    string
+	HomeDir  string
+	Shell    string
+}
+
+// GroupEntry is a row of a group file /etc/group
+type GroupEntry struct {
+	Name    string
+	GID     int64
+	Members []string
+}
+
+// ShadowEntry is a row of a shadow file /etc/shadow, without the password hash.
+// The aging fields are nil when empty.
+type ShadowEntry struct {
+	Username string
+
+	// set, empty or locked
+	PasswordStatus string
+
+	// The crypt(3) algorithm of the password hash, e.g. sha512 or yescrypt. Empty if there is no hash.
+	HashAlgorithm string
+
+	// Days since the epoch of the last password change
+	LastChange *int64
+	MinDays    *int64
+	MaxDays    *int64
+	WarnDays   *int64
+
+	InactiveDays *int64
+
+	// Days since the epoch of the account expiration
+	ExpireDate *int64
+}
+
+// crypt(3) hash prefixes, see crypt(5)
+var passwordHashPrefixes = []struct {
+	prefix    string
+	algorithm string
+}{
+	{prefix: "$y$", algorithm: "yescrypt"},
+	{prefix: "$gy$", algorithm: "gost-yescrypt"},
+	{prefix: "$7$", algorithm: "scrypt"},
+	{prefix: "$2a$", algorithm: "bcrypt"},
+	{prefix: "$2b$", algorithm: "bcrypt"},
+	{prefix: "$2y$", algorithm: "bcrypt"},
+	{prefix: "$6$", algorithm: "sha512"},
+	{prefix: "$5$", algorithm: "sha256"},
+	{prefix: "$sha1$", algorithm: "sha1"},
+	{prefix: "$md5", algorithm: "sun-md5"},
+	{prefix: "$1$", algorithm: "md5"},
+	{prefix: "_", algorithm: "bsdi-des"},
+}
+
+// passwordHashAlgorithm returns the crypt(3) algorithm of `hash`, or empty if it is not a hash
+func passwordHashAlgorithm(hash string) string {
+	for _, p := range passwordHashPrefixes {
+		if strings.HasPrefix(hash, p.prefix) {
+			return p.algorithm
+		}
+	}
+	// traditional DES hashes are 13 chars of [./0-9A-Za-z]
+	if len(hash) == 13 && !strings.ContainsAny(hash, "$*!:") {
+		return "des"
+	}
+	return ""
+}
+
+func parseShadowDays(field string) *int64 {
+	days, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &days
+}
+
+// readColonFileRows returns the colon separated fields of every row of {root}{filePath} with at least `cols` fields.
+// Rows of NIS compat entries (+foo, -foo) are skipped.
+func readColonFileRows(root, filePath string, cols int) ([][]string, error) {
+	f, err := os.Open(root + filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows := [][]string{}
+	_, err = readColonFile(f, func(line []byte) (any, error) {
+		parts := strings.SplitN(string(line), ":", cols)
+		if len(parts) < cols || parts[0] == "" || parts[0][0] == '+' || parts[0][0] == '-' {
+			return nil, nil
+		}
+		rows = append(rows, parts)
+		return nil, nil
+	}, cols-1)
+	return rows, err
+}
+
+// ReadPasswdFile returns the rows of a users file {root}/etc/passwd
+func ReadPasswdFile(root string) ([]PasswdEntry, error) {
+	rows, err := readColonFileRows(root, userFile, 7)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PasswdEntry, 0, len(rows))
+	for _, parts := range rows {
+		uid, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		gid, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		res = append(res, PasswdEntry{
+			Username: parts[0],
+			UID:      uid,
+			GID:      gid,
+			Gecos:    parts[4],
+			HomeDir:  parts[5],
+			Shell:    parts[6],
+		})
+	}
+	return res, nil
+}
+
+// ReadGroupFile returns the rows of a group file {root}/etc/group
+func ReadGroupFile(root string) ([]GroupEntry, error) {
+	rows, err := readColonFileRows(root, groupFile, 4)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]GroupEntry, 0, len(rows))
+	for _, parts := range rows {
+		gid, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		group := GroupEntry{Name: parts[0], GID: gid, Members: []string{}}
+		if parts[3] != "" {
+			group.Members = strings.Split(parts[3], ",")
+		}
+		res = append(res, group)
+	}
+	return res, nil
+}
+
+// ReadShadowFile returns the rows of a shadow file {root}/etc/shadow. The password hashes are not returned.
+func ReadShadowFile(root string) ([]ShadowEntry, error) {
+	rows, err := readColonFileRows(root, shadowFile, 9)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ShadowEntry, 0, len(rows))
+	for _, parts := range rows {
+		entry := ShadowEntry{
+			Username:       parts[0],
+			PasswordStatus: PasswordStatusSet,
+			LastChange:     parseShadowDays(parts[2]),
+			MinDays:        parseShadowDays(parts[3]),
+			MaxDays:        parseShadowDays(parts[4]),
+			WarnDays:       parseShadowDays(parts[5]),
+			InactiveDays:   parseShadowDays(parts[6]),
+			ExpireDate:     parseShadowDays(parts[7]),
+		}
+		hash := parts[1]
+		switch {
+		case hash == "":
+			entry.PasswordStatus = PasswordStatusEmpty
+		case strings.HasPrefix(hash, "!") || strings.HasPrefix(hash, "*"):
+			// a locked password may keep its hash after the `!`
+			entry.PasswordStatus = PasswordStatusLocked
+			hash = strings.TrimLeft(hash, "!*")
+		}
+		entry.HashAlgorithm = passwordHashAlgorithm(hash)
+		res = append(res, entry)
+	}
+	return res, nil
+}
@@ -179,3 +179,43 @@ func Test_LookupGroupByUID(t *testing.T) {
 	}
 
 }
+
+func TestReadPasswdFile(t *testing.T) {
+	users, err := ReadPasswdFile("testdata")
+	assert.NoError(t, err)
+	assert.Len(t, users, 5)
+	assert.Equal(t, PasswdEntry{Username: "sync", UID: 4, GID: 65534, Gecos: "sync", HomeDir: "/bin", Shell: "/bin/sync"}, users[2])
+
+	_, err = ReadPasswdFile("missing")
+	assert.Error(t, err)
+}
+
+func TestReadGroupFile(t *testing.T) {
+	groups, err := ReadGroupFile("testdata")
+	assert.NoError(t, err)
+	assert.Len(t, groups, 4)
+	assert.Equal(t, GroupEntry{Name: "sys", GID: 3, Members: []string{}}, groups[3])
+}
+
+func TestReadShadowFile(t *testing.T) {
+	entries, err := ReadShadowFile("testdata")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5)
+
+	statuses := map[string][2]string{}
+	for _, entry := range entries {
+		statuses[entry.Username] = [2]string{entry.PasswordStatus, entry.HashAlgorithm}
+	}
+	assert.Equal(t, map[string][2]string{
+		"root":  {PasswordStatusSet, "yescrypt"},
+		"sys":   {PasswordStatusLocked, ""},
+		"sync":  {PasswordStatusLocked, "sha512"},
+		"games": {PasswordStatusEmpty, ""},
+		"man":   {PasswordStatusSet, "des"},
+	}, statuses)
+
+	assert.Equal(t, int64(99999), *entries[0].MaxDays)
+	assert.Nil(t, entries[0].InactiveDays)
+	assert.Equal(t, int64(20000), *entries[2].ExpireDate)
+	assert.Nil(t, entries[3].MaxDays)
+}
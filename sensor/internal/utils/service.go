@@ -100,6 +100,77 @@ func GetServiceFilesByPIDSystemd(pid int) (string, error) {
 	return configDir, nil
 }
 
+// SystemdUnitStatus holds the state of a systemd unit, as reported by systemd
+type SystemdUnitStatus struct {
+	Name        string
+	Description string
+
+	// Empty for unit files which are not loaded
+	LoadState   string
+	ActiveState string
+	SubState    string
+
+	// enabled, disabled, static, masked, etc.
+	UnitFileState string
+
+	// The unit file path and its drop-in files, on the host
+	FragmentPath string
+	DropInPaths  []string
+}
+
+// ListSystemdUnits returns the units loaded by systemd, followed by the unit files which are not loaded.
+func ListSystemdUnits(ctx context.Context) ([]SystemdUnitStatus, error) {
+	conn, err := systemd_debus.NewConnection(newSystemDbusConnection)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	loadedUnits, err := conn.ListUnitsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unitFiles, err := conn.ListUnitFilesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]SystemdUnitStatus, 0, len(loadedUnits)+len(unitFiles))
+	loaded := map[string]bool{}
+	for _, loadedUnit := range loadedUnits {
+		loaded[loadedUnit.Name] = true
+		unit := SystemdUnitStatus{
+			Name:        loadedUnit.Name,
+			Description: loadedUnit.Description,
+			LoadState:   loadedUnit.LoadState,
+			ActiveState: loadedUnit.ActiveState,
+			SubState:    loadedUnit.SubState,
+		}
+
+		// query by object path, so units are not loaded by the query
+		properties, err := conn.GetUnitPathPropertiesContext(ctx, loadedUnit.Path)
+		if err != nil {
+			logger.L().Debug("failed to get unit properties", helpers.String("unit", loadedUnit.Name), helpers.Error(err))
+		} else {
+			unit.FragmentPath, _ = properties["FragmentPath"].(string)
+			unit.UnitFileState, _ = properties["UnitFileState"].(string)
+			unit.DropInPaths, _ = properties["DropInPaths"].([]string)
+		}
+		units = append(units, unit)
+	}
+
+	for _, unitFile := range unitFiles {
+		name := path.Base(unitFile.Path)
+		if loaded[name] {
+			continue
+		}
+		loaded[name] = true
+		units = append(units, SystemdUnitStatus{Name: name, UnitFileState: unitFile.Type, FragmentPath: unitFile.Path})
+	}
+
+	return units, nil
+}
+
 // getExistsPath return the first exists path from a list of `paths`, prefixing it with `rootDir`.
 func getExistsPath(rootDir string, paths ...string) string {
 	for _, p := range paths {
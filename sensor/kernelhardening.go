@@ -0,0 +1,112 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+)
+
+const (
+	lsmFileName             = "/sys/kernel/security/lsm"
+	lockdownFileName        = "/sys/kernel/security/lockdown"
+	yamaPtraceScopeFile     = "sys/kernel/yama/ptrace_scope"
+	seccompActionsAvailFile = "sys/kernel/seccomp/actions_avail"
+)
+
+// seccomp modes of /proc/<pid>/status
+var seccompModeNames = map[string]string{
+	"0": "disabled",
+	"1": "strict",
+	"2": "filter",
+}
+
+// parseLockdown returns the selected mode of /sys/kernel/security/lockdown, e.g. `none [integrity] confidentiality`
+func parseLockdown(content string) string {
+	for _, mode := range strings.Fields(content) {
+		if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+			return strings.Trim(mode, "[]")
+		}
+	}
+	return ""
+}
+
+// parseProcStatus returns the fields of a /proc/<pid>/status file
+func parseProcStatus(content []byte) map[string]string {
+	res := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(scanner.Text(), ":")
+		if ok {
+			res[key] = strings.TrimSpace(val)
+		}
+	}
+	return res
+}
+
+// processSeccomp returns the seccomp state of process `pid`
+func processSeccomp(procDir string, pid int32) (*ds.ProcessSeccomp, error) {
+	content, err := os.ReadFile(path.Join(procDir, strconv.Itoa(int(pid)), "status"))
+	if err != nil {
+		return nil, err
+	}
+	status := parseProcStatus(content)
+	mode, ok := status["Seccomp"]
+	if !ok {
+		return nil, fmt.Errorf("seccomp is not supported")
+	}
+	res := ds.ProcessSeccomp{PID: pid, Mode: seccompModeNames[mode]}
+	if filters, err := strconv.Atoi(status["Seccomp_filters"]); err == nil {
+		res.Filters = &filters
+	}
+	return &res, nil
+}
+
+// senseSeccomp reads the seccomp support of the kernel, and the seccomp state of the node critical processes
+func senseSeccomp(procDir string) *ds.SeccompStatus {
+	res := ds.SeccompStatus{}
+
+	// the Seccomp field exists if the kernel is built with CONFIG_SECCOMP
+	if content, err := os.ReadFile(path.Join(procDir, "1", "status")); err == nil {
+		_, res.Supported = parseProcStatus(content)["Seccomp"]
+	}
+	// actions_avail exists if the kernel is built with CONFIG_SECCOMP_FILTER
+	if content, err := os.ReadFile(path.Join(procDir, seccompActionsAvailFile)); err == nil {
+		res.FilterSupported = true
+		res.Actions = strings.Fields(string(content))
+	}
+
+	for _, proc := range findNodeCriticalProcesses(procDir) {
+		seccomp, err := processSeccomp(procDir, proc.pid)
+		if err != nil {
+			continue
+		}
+		seccomp.Name = proc.name
+		res.Processes = append(res.Processes, *seccomp)
+	}
+	return &res
+}
+
+// senseKernelHardening fills the LSM stack, lockdown, Yama and seccomp state of the host file system at `rootDir`
+// and the proc file system at `procDir`
+func senseKernelHardening(rootDir, procDir string, res *ds.LinuxSecurityHardeningStatus) {
+	if content, err := os.ReadFile(path.Join(rootDir, lsmFileName)); err == nil {
+		if lsms := strings.Trim(strings.TrimSpace(string(content)), ","); lsms != "" {
+			res.LSMs = strings.Split(lsms, ",")
+		}
+	}
+	if content, err := os.ReadFile(path.Join(rootDir, lockdownFileName)); err == nil {
+		res.Lockdown = parseLockdown(string(content))
+	}
+	if content, err := os.ReadFile(path.Join(procDir, yamaPtraceScopeFile)); err == nil {
+		if scope, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil {
+			res.YamaPtraceScope = &scope
+		}
+	}
+	res.Seccomp = senseSeccomp(procDir)
+}
@@ -0,0 +1,46 @@
+package sensor
+
+import (
+	"testing"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseLockdown(t *testing.T) {
+	assert.Equal(t, "integrity", parseLockdown("none [integrity] confidentiality\n"))
+	assert.Equal(t, "none", parseLockdown("[none] integrity confidentiality"))
+	assert.Equal(t, "", parseLockdown(""))
+}
+
+func Test_senseKernelHardening(t *testing.T) {
+	res := ds.LinuxSecurityHardeningStatus{}
+	senseKernelHardening("testdata/kernelhardening/root", "testdata/kernelhardening/proc", &res)
+
+	assert.Equal(t, []string{"lockdown", "capability", "landlock", "yama", "apparmor", "bpf"}, res.LSMs)
+	assert.Equal(t, "integrity", res.Lockdown)
+	require.NotNil(t, res.YamaPtraceScope)
+	assert.Equal(t, 1, *res.YamaPtraceScope)
+
+	require.NotNil(t, res.Seccomp)
+	assert.True(t, res.Seccomp.Supported)
+	assert.True(t, res.Seccomp.FilterSupported)
+	assert.Contains(t, res.Seccomp.Actions, "user_notif")
+	zero, one := 0, 1
+	assert.Equal(t, []ds.ProcessSeccomp{
+		{Name: "kubelet", PID: 500, Mode: "disabled", Filters: &zero},
+		{Name: "containerd", PID: 501, Mode: "filter", Filters: &one},
+	}, res.Seccomp.Processes)
+}
+
+func Test_senseKernelHardeningMissing(t *testing.T) {
+	res := ds.LinuxSecurityHardeningStatus{}
+	senseKernelHardening("testdata/kernelhardening/missing", "testdata/kernelhardening/missing", &res)
+
+	assert.Nil(t, res.LSMs)
+	assert.Empty(t, res.Lockdown)
+	assert.Nil(t, res.YamaPtraceScope)
+	assert.False(t, res.Seccomp.Supported)
+	assert.Empty(t, res.Seccomp.Processes)
+}
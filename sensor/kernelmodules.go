@@ -0,0 +1,276 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	// kernel module states of the CIS controls
+	KernelModuleLoaded      = "loaded"
+	KernelModuleBuiltin     = "builtin"
+	KernelModuleDisabled    = "disabled"
+	KernelModuleLoadable    = "loadable"
+	KernelModuleUnavailable = "unavailable"
+
+	// the module taint flag of a module which failed the signature check
+	unsignedModuleTaint = "E"
+
+	sigEnforceFileName = "/sys/module/module/parameters/sig_enforce"
+)
+
+// modprobe.d directories, by precedence: a file overrides the files with the same name in the next directories
+var modprobeConfDirs = []string{
+	"/etc/modprobe.d",
+	"/run/modprobe.d",
+	"/usr/local/lib/modprobe.d",
+	"/usr/lib/modprobe.d",
+	"/lib/modprobe.d",
+}
+
+// kernel module directories, holding a `<release>` dir
+var kernelModulesDirs = []string{
+	"/lib/modules",
+	"/usr/lib/modules",
+}
+
+// The file systems and network protocols which CIS recommends to disable
+var cisDisabledKernelModules = []string{"cramfs", "squashfs", "udf", "dccp", "sctp", "rds", "tipc"}
+
+// KernelModule holds a loaded kernel module, from /proc/modules
+type KernelModule struct {
+	Name     string `json:"name"`
+	Size     uint64 `json:"size"`
+	RefCount int    `json:"refCount"`
+
+	// The modules which depend on this module
+	UsedBy []string `json:"usedBy,omitempty"`
+
+	// Live, Loading or Unloading
+	State string `json:"state"`
+
+	// The taint flags of the module
+	// Example: OE
+	Taints string `json:"taints,omitempty"`
+
+	// false if the module failed the signature check. nil if the kernel doesn't check module signatures.
+	Signed *bool `json:"signed,omitempty"`
+}
+
+// ModprobeDirective holds a `blacklist` or `install` directive of a modprobe.d config file
+type ModprobeDirective struct {
+	Path string `json:"path"`
+
+	// blacklist or install
+	Directive string `json:"directive"`
+	Module    string `json:"module"`
+
+	// The command of an install directive
+	// Example: /bin/false
+	Command string `json:"command,omitempty"`
+}
+
+// KernelModuleControl holds the state of a kernel module which CIS recommends to disable
+type KernelModuleControl struct {
+	Module string `json:"module"`
+
+	// loaded, builtin, disabled (its install command is /bin/false or /bin/true), loadable or unavailable
+	Status string `json:"status"`
+
+	Blacklisted bool `json:"blacklisted"`
+}
+
+// KernelModulesInfo holds the loaded kernel modules and the modprobe configuration of the host
+type KernelModulesInfo struct {
+	Modules []KernelModule `json:"modules"`
+
+	// true if the kernel loads only signed modules. nil if the kernel doesn't check module signatures.
+	SigEnforce *bool `json:"sigEnforce,omitempty"`
+
+	Directives []ModprobeDirective   `json:"directives"`
+	Controls   []KernelModuleControl `json:"controls"`
+}
+
+// normalizeModuleName returns the name of a module as the kernel reports it, with underscores instead of dashes
+func normalizeModuleName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// parseProcModules parses the content of /proc/modules.
+// Example line: `nvidia 56807424 1 nvidia_modeset, Live 0x0000000000000000 (POE)`
+func parseProcModules(content []byte) []KernelModule {
+	res := make([]KernelModule, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		module := KernelModule{Name: fields[0], State: fields[4]}
+		module.Size, _ = strconv.ParseUint(fields[1], 10, 64)
+		module.RefCount, _ = strconv.Atoi(fields[2])
+		if fields[3] != "-" {
+			module.UsedBy = strings.FieldsFunc(fields[3], func(r rune) bool { return r == ',' })
+		}
+		if last := fields[len(fields)-1]; len(fields) > 6 && strings.HasPrefix(last, "(") {
+			module.Taints = strings.Trim(last, "()")
+		}
+		res = append(res, module)
+	}
+	return res
+}
+
+// parseModprobeConf returns the blacklist and install directives of a modprobe.d config file
+func parseModprobeConf(content []byte, filePath string) []ModprobeDirective {
+	res := make([]ModprobeDirective, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "blacklist":
+			res = append(res, ModprobeDirective{Path: filePath, Directive: fields[0], Module: normalizeModuleName(fields[1])})
+		case "install":
+			res = append(res, ModprobeDirective{
+				Path:      filePath,
+				Directive: fields[0],
+				Module:    normalizeModuleName(fields[1]),
+				Command:   strings.Join(fields[2:], " "),
+			})
+		}
+	}
+	return res
+}
+
+// isDisablingInstallCommand returns true if an install command prevents loading the module, e.g. `/bin/false`
+func isDisablingInstallCommand(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	exe := path.Base(fields[0])
+	return exe == "false" || exe == "true"
+}
+
+// readKernelModulesList returns the names of the modules listed in `modules.dep` or `modules.builtin`
+// of kernel `release`
+func readKernelModulesList(rootDir, release, fileName string) map[string]bool {
+	res := map[string]bool{}
+	for _, dir := range kernelModulesDirs {
+		content, err := os.ReadFile(path.Join(rootDir, dir, release, fileName))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			// Example: kernel/fs/cramfs/cramfs.ko.zst: kernel/lib/zlib.ko
+			modulePath, _, _ := strings.Cut(scanner.Text(), ":")
+			name, _, _ := strings.Cut(path.Base(strings.TrimSpace(modulePath)), ".ko")
+			if name != "" {
+				res[normalizeModuleName(name)] = true
+			}
+		}
+		break
+	}
+	return res
+}
+
+// senseKernelModules reads the loaded modules under `procDir`, and the module files and modprobe.d
+// config files of the host file system at `rootDir`
+func senseKernelModules(ctx context.Context, rootDir, procDir string) (*KernelModulesInfo, error) {
+	ret := KernelModulesInfo{
+		Modules:    make([]KernelModule, 0),
+		Directives: make([]ModprobeDirective, 0),
+		Controls:   make([]KernelModuleControl, 0, len(cisDisabledKernelModules)),
+	}
+
+	content, err := os.ReadFile(path.Join(procDir, "modules"))
+	if err != nil {
+		return &ret, err
+	}
+	ret.Modules = parseProcModules(content)
+
+	// the parameter exists if the kernel is built with CONFIG_MODULE_SIG
+	if sigEnforce, err := os.ReadFile(path.Join(rootDir, sigEnforceFileName)); err == nil {
+		enforce := strings.TrimSpace(string(sigEnforce)) == "Y"
+		ret.SigEnforce = &enforce
+		for i := range ret.Modules {
+			signed := !strings.Contains(ret.Modules[i].Taints, unsignedModuleTaint)
+			ret.Modules[i].Signed = &signed
+		}
+	}
+
+	for _, confFile := range overlayConfFiles(rootDir, modprobeConfDirs, ".conf") {
+		content, err := readHostConfFile(rootDir, confFile)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to read modprobe config file", helpers.String("path", confFile), helpers.Error(err))
+			continue
+		}
+		ret.Directives = append(ret.Directives, parseModprobeConf(content, confFile)...)
+	}
+
+	var available, builtin map[string]bool
+	if release, err := os.ReadFile(path.Join(procDir, "sys", "kernel", "osrelease")); err == nil {
+		available = readKernelModulesList(rootDir, strings.TrimSpace(string(release)), "modules.dep")
+		builtin = readKernelModulesList(rootDir, strings.TrimSpace(string(release)), "modules.builtin")
+	} else {
+		logger.L().Ctx(ctx).Warning("failed to read kernel release", helpers.Error(err))
+	}
+
+	loaded := map[string]bool{}
+	for _, module := range ret.Modules {
+		loaded[module.Name] = true
+	}
+	for _, name := range cisDisabledKernelModules {
+		control := KernelModuleControl{Module: name, Status: KernelModuleUnavailable}
+		disabled := false
+		// the first install directive of a module is the effective one
+		installFound := false
+		for _, directive := range ret.Directives {
+			if directive.Module != name {
+				continue
+			}
+			switch {
+			case directive.Directive == "blacklist":
+				control.Blacklisted = true
+			case !installFound:
+				installFound = true
+				disabled = isDisablingInstallCommand(directive.Command)
+			}
+		}
+		switch {
+		case loaded[name]:
+			control.Status = KernelModuleLoaded
+		case builtin[name]:
+			control.Status = KernelModuleBuiltin
+		case disabled:
+			control.Status = KernelModuleDisabled
+		case available[name]:
+			control.Status = KernelModuleLoadable
+		}
+		ret.Controls = append(ret.Controls, control)
+	}
+
+	return &ret, nil
+}
+
+// SenseKernelModules returns the loaded kernel modules with their taint flags and signature status, the modprobe.d
+// blacklist and install directives, and whether the modules which CIS recommends to disable are loaded, loadable or disabled
+func SenseKernelModules(ctx context.Context) (*KernelModulesInfo, error) {
+	return senseKernelModules(ctx, utils.HostFileSystemDefaultLocation, procDirName)
+}
@@ -0,0 +1,44 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseProcModules(t *testing.T) {
+	modules := parseProcModules([]byte("nvidia 56807424 1 nvidia_modeset,nvidia_uvm, Live 0x0000000000000000 (POE)\noverlay 196608 12 - Live 0x0000000000000000\n"))
+	require.Len(t, modules, 2)
+	assert.Equal(t, KernelModule{Name: "nvidia", Size: 56807424, RefCount: 1, UsedBy: []string{"nvidia_modeset", "nvidia_uvm"}, State: "Live", Taints: "POE"}, modules[0])
+	assert.Equal(t, KernelModule{Name: "overlay", Size: 196608, RefCount: 12, State: "Live"}, modules[1])
+}
+
+func Test_senseKernelModules(t *testing.T) {
+	info, err := senseKernelModules(context.TODO(), "testdata/kernelmodules/root", "testdata/kernelmodules/proc")
+	require.NoError(t, err)
+
+	require.Len(t, info.Modules, 4)
+	require.NotNil(t, info.SigEnforce)
+	assert.False(t, *info.SigEnforce)
+	assert.False(t, *info.Modules[1].Signed)
+	assert.True(t, *info.Modules[3].Signed)
+
+	// /lib/modprobe.d/blacklist.conf is overridden by /etc/modprobe.d/blacklist.conf
+	assert.Equal(t, ModprobeDirective{Path: "/etc/modprobe.d/blacklist.conf", Directive: "blacklist", Module: "tipc"}, info.Directives[0])
+	assert.Equal(t, ModprobeDirective{Path: "/etc/modprobe.d/cis.conf", Directive: "install", Module: "cramfs", Command: "/bin/false"}, info.Directives[1])
+	// /etc/modprobe.d/rds.conf is an absolute link, resolved in the host file system
+	assert.Contains(t, info.Directives, ModprobeDirective{Path: "/etc/modprobe.d/rds.conf", Directive: "blacklist", Module: "rds"})
+	assert.Len(t, info.Directives, 8)
+
+	assert.Equal(t, []KernelModuleControl{
+		{Module: "cramfs", Status: KernelModuleDisabled, Blacklisted: true},
+		{Module: "squashfs", Status: KernelModuleBuiltin},
+		{Module: "udf", Status: KernelModuleDisabled},
+		{Module: "dccp", Status: KernelModuleDisabled, Blacklisted: true},
+		{Module: "sctp", Status: KernelModuleLoaded, Blacklisted: true},
+		{Module: "rds", Status: KernelModuleLoadable, Blacklisted: true},
+		{Module: "tipc", Status: KernelModuleLoadable, Blacklisted: true},
+	}, info.Controls)
+}
@@ -1,106 +1,310 @@
 package sensor
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
 )
 
 const (
+	procSysDir       = "/proc/sys"
 	procSysKernelDir = "/proc/sys/kernel"
+
+	procKernelConfigFileName = "/proc/config.gz"
+	bootDirName              = "/boot"
 	//TODO: add dir for macos (?)
 	//TODO: add dir for windows (?)
 )
 
+// Sysctls which are not reported by default: they change on every read, or are large per neighbour tables
+var defaultSysctlDenyList = []string{
+	"kernel.random.uuid",
+	"kernel.random.boot_id",
+	"kernel.random.entropy_avail",
+	"kernel.ns_last_pid",
+	"fs.binfmt_misc",
+	"net.ipv4.neigh",
+	"net.ipv6.neigh",
+}
+
+// Kernel build options which are reported: an option, or a prefix ending with `_`
+var securityKernelConfPrefixes = []string{
+	"CONFIG_SECURITY_",
+	"CONFIG_SECURITY",
+	"CONFIG_LSM",
+	"CONFIG_DEFAULT_SECURITY_",
+	"CONFIG_MODULE_SIG",
+	"CONFIG_MODULE_SIG_",
+	"CONFIG_BPF_UNPRIV_DEFAULT_OFF",
+	"CONFIG_BPF_JIT_ALWAYS_ON",
+	"CONFIG_USER_NS",
+	"CONFIG_SECCOMP",
+	"CONFIG_SECCOMP_FILTER",
+	"CONFIG_STRICT_DEVMEM",
+	"CONFIG_IO_STRICT_DEVMEM",
+	"CONFIG_DEVMEM",
+	"CONFIG_HARDENED_USERCOPY",
+	"CONFIG_FORTIFY_SOURCE",
+	"CONFIG_STACKPROTECTOR",
+	"CONFIG_STACKPROTECTOR_STRONG",
+	"CONFIG_RANDOMIZE_BASE",
+	"CONFIG_RANDOMIZE_MEMORY",
+	"CONFIG_STRICT_KERNEL_RWX",
+	"CONFIG_STRICT_MODULE_RWX",
+	"CONFIG_PAGE_TABLE_ISOLATION",
+	"CONFIG_RETPOLINE",
+	"CONFIG_INIT_ON_ALLOC_DEFAULT_ON",
+	"CONFIG_INIT_ON_FREE_DEFAULT_ON",
+	"CONFIG_KEXEC",
+	"CONFIG_HIBERNATION",
+	"CONFIG_LEGACY_VSYSCALL_NONE",
+	"CONFIG_IMA",
+	"CONFIG_EVM",
+	"CONFIG_AUDIT",
+	"CONFIG_IKCONFIG_PROC",
+}
+
 type KernelVariable struct {
 	Key    string `json:"key"`
 	Value  string `json:"value"`
 	Source string `json:"source"`
 }
 
-func SenseProcSysKernel(ctx context.Context) ([]KernelVariable, error) {
+// SysctlFilter selects the sysctls to report by their dotted name prefixes, e.g. `net.ipv4` matches `net.ipv4.ip_forward`
+type SysctlFilter struct {
+	// If not empty, only sysctls which match one of the prefixes are reported
+	Allow []string
 
-	// open system kernel directory (only Linux OS)
-	procDir, err := os.Open(procSysKernelDir)
+	// Sysctls which match one of the prefixes are not reported
+	Deny []string
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to procSysKernelDir dir(%s): %v", procSysKernelDir, err)
+// NewSysctlFilter returns a filter from the `allow` and `deny` query parameters (comma separated prefixes).
+// The deny list extends the default deny list.
+func NewSysctlFilter(values url.Values) *SysctlFilter {
+	splitList := func(list string) []string {
+		res := []string{}
+		for _, prefix := range strings.Split(list, ",") {
+			if prefix = strings.Trim(strings.TrimSpace(prefix), "."); prefix != "" {
+				res = append(res, strings.ReplaceAll(prefix, "/", "."))
+			}
+		}
+		return res
+	}
+	return &SysctlFilter{
+		Allow: splitList(values.Get("allow")),
+		Deny:  append(slices.Clone(defaultSysctlDenyList), splitList(values.Get("deny"))...),
+	}
+}
+
+func sysctlHasPrefix(name, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+".")
+}
+
+// denied returns true if the sysctl `name`, or all the sysctls under it, should not be reported
+func (f *SysctlFilter) denied(name string) bool {
+	for _, prefix := range f.Deny {
+		if sysctlHasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns true if the sysctl `name` should be reported. If `isDir`, returns true if sysctls under it may be.
+func (f *SysctlFilter) allowed(name string, isDir bool) bool {
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, prefix := range f.Allow {
+		if sysctlHasPrefix(name, prefix) || (isDir && sysctlHasPrefix(prefix, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sysctlName returns the dotted name of the sysctl file `relPath`, relative to /proc/sys
+func sysctlName(relPath string) string {
+	return strings.ReplaceAll(strings.Trim(relPath, "/"), "/", ".")
+}
+
+// sysctlPath returns the path of the sysctl `name` relative to /proc/sys. Both `.` and `/` separators are accepted.
+func sysctlPath(name string) string {
+	if strings.Contains(name, "/") {
+		return strings.Trim(name, "/")
 	}
-	defer procDir.Close()
+	return strings.ReplaceAll(name, ".", "/")
+}
 
-	return walkVarsDir(ctx, procSysKernelDir, procDir)
+func SenseProcSysKernel(ctx context.Context) ([]KernelVariable, error) {
+	return walkVarsDir(ctx, procSysKernelDir, "kernel", &SysctlFilter{})
 }
 
-func walkVarsDir(ctx context.Context, dirPath string, procDir *os.File) ([]KernelVariable, error) {
-	var varsNames []string
+// walkVarsDir returns the variables under `dirPath`, which holds the sysctls under `dirName` (e.g. kernel),
+// and match `filter`. The source of a variable is its path. The key of a kernel variable is its file name, as consumers
+// expect, and the key of other variables is their dotted name, since their file names repeat (e.g. rp_filter).
+func walkVarsDir(ctx context.Context, dirPath string, dirName string, filter *SysctlFilter) ([]KernelVariable, error) {
 	varsList := make([]KernelVariable, 0, 128)
 
-	var err error
-	for varsNames, err = procDir.Readdirnames(100); err == nil; varsNames, err = procDir.Readdirnames(100) {
-		for varIdx := range varsNames {
-			varFileName := path.Join(dirPath, varsNames[varIdx])
-			varFile, errOpen := os.Open(varFileName)
-			if errOpen != nil {
-				if strings.Contains(errOpen.Error(), "permission denied") {
-					logger.L().Ctx(ctx).Warning("In walkVarsDir failed to open file", helpers.String("varFileName", varFileName),
-						helpers.Error(errOpen))
-					continue
-				}
-				return nil, fmt.Errorf("failed to open file (%s): %v", varFileName, err)
+	err := filepath.WalkDir(dirPath, func(varFileName string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if varFileName == dirPath {
+				return err
+			}
+			logger.L().Ctx(ctx).Warning("In walkVarsDir failed to read dir", helpers.String("varFileName", varFileName),
+				helpers.Error(err))
+			return nil
+		}
+		if varFileName == dirPath {
+			return nil
+		}
+		relPath, _ := filepath.Rel(dirPath, varFileName)
+		name := sysctlName(path.Join(dirName, relPath))
+		if filter.denied(name) || !filter.allowed(name, entry.IsDir()) {
+			if entry.IsDir() {
+				return fs.SkipDir
 			}
-			defer varFile.Close()
-			fileInfo, errStat := varFile.Stat()
-			if err != nil {
-				return nil, fmt.Errorf("failed to stat file (%s): %v", varFileName, errStat)
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(varFileName)
+		if err != nil {
+			// write only variables, e.g. vm/drop_caches, can't be read
+			logger.L().Ctx(ctx).Debug("In walkVarsDir failed to read file", helpers.String("varFileName", varFileName),
+				helpers.Error(err))
+			return nil
+		}
+		key := name
+		if sysctlHasPrefix(name, "kernel") {
+			key = entry.Name()
+		}
+		varsList = append(varsList, KernelVariable{
+			Key:    key,
+			Value:  string(content),
+			Source: varFileName,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk dir(%s): %w; found so far: %v", dirPath, err, varsList)
+	}
+	return varsList, nil
+}
+
+// SenseProcSys returns the variables under /proc/sys which match `filter`.
+// The net variables are per network namespace, so /proc/sys is read from the host network namespace.
+func SenseProcSys(ctx context.Context, filter *SysctlFilter) ([]KernelVariable, error) {
+	var vars []KernelVariable
+	var walkErr error
+	err := utils.RunInNetNs(hostNetNsProcPath("ns", "net"), func() error {
+		vars, walkErr = walkVarsDir(ctx, procSysDir, "", filter)
+		return nil
+	})
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseProcSys failed to join host network namespace, net variables are of the pod network namespace",
+			helpers.Error(err))
+		vars, walkErr = walkVarsDir(ctx, procSysDir, "", filter)
+	}
+	return vars, walkErr
+}
+
+// isSecurityKernelConf returns true if the kernel build option `key` is security relevant
+func isSecurityKernelConf(key string) bool {
+	for _, prefix := range securityKernelConfPrefixes {
+		if key == prefix || (strings.HasSuffix(prefix, "_") && strings.HasPrefix(key, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKernelConfig returns the security relevant options of a kernel build config.
+// Options which are not set (`# CONFIG_X is not set`) have the value `n`.
+func parseKernelConfig(content []byte, source string) []KernelVariable {
+	varsList := make([]KernelVariable, 0, 64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var key, value string
+		if unset, ok := strings.CutPrefix(line, "# "); ok {
+			if key, ok = strings.CutSuffix(unset, " is not set"); !ok {
+				continue
 			}
-			if fileInfo.IsDir() {
-				// CAUTION: recursive call!!!
-				innerVars, errW := walkVarsDir(ctx, varFileName, varFile)
-				if errW != nil {
-					return nil, fmt.Errorf("failed to walkVarsDir file (%s): %v", varFileName, errW)
-				}
-				if len(innerVars) > 0 {
-					varsList = append(varsList, innerVars...)
-				}
-			} else if fileInfo.Mode().IsRegular() {
-				strBld := strings.Builder{}
-				if _, errCopy := io.Copy(&strBld, varFile); err != nil {
-					if strings.Contains(errCopy.Error(), "operation not permitted") {
-						logger.L().Ctx(ctx).Warning("In walkVarsDir failed to Copy file", helpers.String("varFileName", varFileName),
-							helpers.Error(errCopy))
-						continue
-					}
-					return nil, fmt.Errorf("failed to copy file (%s): %w", varFileName, errCopy)
-				}
-				varsList = append(varsList, KernelVariable{
-					Key:    varsNames[varIdx],
-					Value:  strBld.String(),
-					Source: varFileName,
-				})
+			value = "n"
+		} else {
+			if key, value, ok = strings.Cut(line, "="); !ok {
+				continue
 			}
+			value = strings.Trim(value, `"`)
+		}
+		if !strings.HasPrefix(key, "CONFIG_") || !isSecurityKernelConf(key) {
+			continue
 		}
+		varsList = append(varsList, KernelVariable{Key: key, Value: value, Source: source})
 	}
-	if err != io.EOF {
-		return nil, fmt.Errorf("failed to Readdirnames of procSysKernelDir dir(%s): %w; found so far: %v", procSysKernelDir, err, varsList)
+	return varsList
+}
+
+// readKernelConfig reads the build config of the running kernel from `/proc/config.gz` under `procDir`,
+// or from `/boot/config-<release>` of the host file system at `rootDir`. Returns the content and its source.
+func readKernelConfig(procDir, rootDir string) ([]byte, string, error) {
+	configGz, err := os.Open(path.Join(procDir, "config.gz"))
+	if err == nil {
+		defer configGz.Close()
+		reader, err := gzip.NewReader(configGz)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open %s: %w", procKernelConfigFileName, err)
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", procKernelConfigFileName, err)
+		}
+		return content, procKernelConfigFileName, nil
 	}
-	return varsList, nil
+
+	release, err := os.ReadFile(path.Join(procDir, "sys", "kernel", "osrelease"))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read kernel release: %w", err)
+	}
+	bootConfig := path.Join(bootDirName, "config-"+strings.TrimSpace(string(release)))
+	content, err := os.ReadFile(path.Join(rootDir, bootConfig))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read kernel config: %w", err)
+	}
+	return content, bootConfig, nil
 }
 
+// SenseKernelConfs returns the security relevant build options of the running kernel
 func SenseKernelConfs() ([]KernelVariable, error) {
-	varsList := make([]KernelVariable, 0, 16)
-
-	return varsList, nil
+	content, source, err := readKernelConfig(procDirName, utils.HostFileSystemDefaultLocation)
+	if err != nil {
+		return make([]KernelVariable, 0), err
+	}
+	return parseKernelConfig(content, source), nil
 }
 
-func SenseKernelVariables(ctx context.Context) ([]KernelVariable, error) {
-	vars, err := SenseProcSysKernel(ctx)
-	if confVars, e := SenseKernelConfs(); err != nil {
+// SenseKernelVariables returns the variables under /proc/sys which match `filter`, and the kernel build configuration
+func SenseKernelVariables(ctx context.Context, filter *SysctlFilter) ([]KernelVariable, error) {
+	vars, err := SenseProcSys(ctx, filter)
+	if confVars, e := SenseKernelConfs(); e != nil {
 		logger.L().Ctx(ctx).Warning("In SenseKernelVariables failed to SenseKernelConfs", helpers.Error(e))
 	} else {
 		vars = append(vars, confVars...)
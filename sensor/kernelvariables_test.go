@@ -3,8 +3,12 @@ package sensor
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"runtime"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSenseProcSysKernel(t *testing.T) {
@@ -29,3 +33,80 @@ func TestSenseProcSysKernel(t *testing.T) {
 		fmt.Printf("Tests are running over OS: %s.\n", osVar)
 	}
 }
+
+func Test_walkVarsDir(t *testing.T) {
+	keys := func(vars []KernelVariable) []string {
+		res := []string{}
+		for _, v := range vars {
+			res = append(res, v.Source)
+		}
+		return res
+	}
+
+	// the default deny list skips the neighbour tables and kernel.random.uuid
+	vars, err := walkVarsDir(context.TODO(), "testdata/sysctl/proc/sys", "", NewSysctlFilter(url.Values{}))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"testdata/sysctl/proc/sys/fs/protected_symlinks",
+		"testdata/sysctl/proc/sys/kernel/randomize_va_space",
+		"testdata/sysctl/proc/sys/net/ipv4/conf/all/rp_filter",
+		"testdata/sysctl/proc/sys/net/ipv4/ip_forward",
+		"testdata/sysctl/proc/sys/vm/overcommit_memory",
+	}, keys(vars))
+	for _, v := range vars {
+		switch v.Source {
+		case "testdata/sysctl/proc/sys/kernel/randomize_va_space":
+			assert.Equal(t, "randomize_va_space", v.Key)
+		case "testdata/sysctl/proc/sys/net/ipv4/conf/all/rp_filter":
+			assert.Equal(t, "net.ipv4.conf.all.rp_filter", v.Key)
+		}
+	}
+
+	vars, err = walkVarsDir(context.TODO(), "testdata/sysctl/proc/sys", "",
+		NewSysctlFilter(url.Values{"allow": {"net.ipv4,vm"}, "deny": {"net/ipv4/conf"}}))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"testdata/sysctl/proc/sys/net/ipv4/ip_forward",
+		"testdata/sysctl/proc/sys/vm/overcommit_memory",
+	}, keys(vars))
+	assert.Equal(t, KernelVariable{Key: "net.ipv4.ip_forward", Value: "1\n", Source: "testdata/sysctl/proc/sys/net/ipv4/ip_forward"}, vars[0])
+}
+
+func Test_readKernelConfig(t *testing.T) {
+	expected := []KernelVariable{
+		{Key: "CONFIG_USER_NS", Value: "y"},
+		{Key: "CONFIG_BPF_UNPRIV_DEFAULT_OFF", Value: "y"},
+		{Key: "CONFIG_MODULE_SIG", Value: "y"},
+		{Key: "CONFIG_MODULE_SIG_FORCE", Value: "n"},
+		{Key: "CONFIG_MODULE_SIG_HASH", Value: "sha512"},
+		{Key: "CONFIG_SECCOMP", Value: "y"},
+		{Key: "CONFIG_SECCOMP_FILTER", Value: "y"},
+		{Key: "CONFIG_SECURITY", Value: "y"},
+		{Key: "CONFIG_SECURITY_APPARMOR", Value: "y"},
+		{Key: "CONFIG_SECURITY_SELINUX_DISABLE", Value: "n"},
+		{Key: "CONFIG_LSM", Value: "landlock,lockdown,yama,integrity,apparmor"},
+	}
+	withSource := func(source string) []KernelVariable {
+		res := []KernelVariable{}
+		for _, v := range expected {
+			v.Source = source
+			res = append(res, v)
+		}
+		return res
+	}
+
+	// /proc/config.gz
+	content, source, err := readKernelConfig("testdata/kernelconfig/procgz", "testdata/kernelconfig/root")
+	require.NoError(t, err)
+	assert.Equal(t, "/proc/config.gz", source)
+	assert.Equal(t, withSource(source), parseKernelConfig(content, source))
+
+	// /boot/config-<release>
+	content, source, err = readKernelConfig("testdata/kernelconfig/proc", "testdata/kernelconfig/root")
+	require.NoError(t, err)
+	assert.Equal(t, "/boot/config-6.8.0-45-generic", source)
+	assert.Equal(t, withSource(source), parseKernelConfig(content, source))
+
+	_, _, err = readKernelConfig("testdata/kernelconfig/proc", "testdata/kernelconfig/missing")
+	assert.Error(t, err)
+}
@@ -3,14 +3,46 @@ package sensor
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
 
+	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
 	ds "github.com/kubescape/host-scanner/sensor/datastructures"
 	"github.com/kubescape/host-scanner/sensor/internal/utils"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	kubeProxyExe = "kube-proxy"
+
+	kubeProxyConfigArgName               = "--config"
+	kubeProxyModeArgName                 = "--proxy-mode"
+	kubeProxyClusterCIDRArgName          = "--cluster-cidr"
+	kubeProxyMetricsBindAddressArgName   = "--metrics-bind-address"
+	kubeProxyHealthzBindAddressArgName   = "--healthz-bind-address"
+	kubeProxyConntrackMaxPerCoreArgName  = "--conntrack-max-per-core"
+	kubeProxyConntrackMinArgName         = "--conntrack-min"
+	kubeProxyConntrackEstablishedArgName = "--conntrack-tcp-timeout-established"
+	kubeProxyConntrackCloseWaitArgName   = "--conntrack-tcp-timeout-close-wait"
+	kubeProxyIPVSSchedulerArgName        = "--ipvs-scheduler"
+	kubeProxyIPVSStrictARPArgName        = "--ipvs-strict-arp"
+
+	// defaults of kube-proxy on linux
+	kubeProxyDefaultMode                 = "iptables"
+	kubeProxyDefaultMetricsBindAddress   = "127.0.0.1:10249"
+	kubeProxyDefaultHealthzBindAddress   = "0.0.0.0:10256"
+	kubeProxyDefaultIPVSScheduler        = "rr"
+	kubeProxyDefaultConntrackMaxPerCore  = 32768
+	kubeProxyDefaultConntrackMin         = 131072
+	kubeProxyDefaultConntrackEstablished = "24h0m0s"
+	kubeProxyDefaultConntrackCloseWait   = "1h0m0s"
+
+	KubeProxyModeIPVS = "ipvs"
+
+	kubeProxyConfigSourceDefault    = "default"
+	kubeProxyConfigSourceFlag       = "flag"
+	kubeProxyConfigSourceConfigFile = "configFile"
 )
 
 // KubeProxyInfo holds information about kube-proxy process
@@ -18,10 +50,189 @@ type KubeProxyInfo struct {
 	// Information about the kubeconfig file of kube-proxy
 	KubeConfigFile *ds.FileInfo `json:"kubeConfigFile,omitempty"`
 
+	// Information about the config file of kube-proxy (if exist)
+	ConfigFile *ds.FileInfo `json:"configFile,omitempty"`
+
+	// The effective configuration of kube-proxy
+	Config *KubeProxyConfig `json:"config,omitempty"`
+
 	// Raw cmd line of kubelet process
 	CmdLine string `json:"cmdLine"`
 }
 
+// KubeProxyConfig holds the effective configuration of kube-proxy,
+// resolved from the config file, the flags and the defaults
+type KubeProxyConfig struct {
+	// Where the configuration was taken from: default, flag or configFile
+	Source string `json:"source"`
+
+	// The proxy mode: iptables, ipvs or nftables
+	Mode string `json:"mode"`
+
+	// Example: 10.244.0.0/16
+	ClusterCIDR string `json:"clusterCIDR,omitempty"`
+
+	// Example: 127.0.0.1:10249
+	MetricsBindAddress string `json:"metricsBindAddress"`
+
+	// true if the metrics endpoint listens on all interfaces (e.g. 0.0.0.0:10249)
+	MetricsBindAllInterfaces bool `json:"metricsBindAllInterfaces"`
+
+	// Example: 0.0.0.0:10256
+	HealthzBindAddress string `json:"healthzBindAddress"`
+
+	// true if the healthz endpoint listens on all interfaces (e.g. 0.0.0.0:10256)
+	HealthzBindAllInterfaces bool `json:"healthzBindAllInterfaces"`
+
+	Conntrack KubeProxyConntrackConfig `json:"conntrack"`
+
+	// The IPVS configuration, only in ipvs mode
+	IPVS *KubeProxyIPVSConfig `json:"ipvs,omitempty"`
+}
+
+// KubeProxyConntrackConfig holds the conntrack settings of kube-proxy
+type KubeProxyConntrackConfig struct {
+	MaxPerCore *int32 `json:"maxPerCore,omitempty"`
+	Min        *int32 `json:"min,omitempty"`
+
+	// Example: 24h0m0s
+	TCPEstablishedTimeout string `json:"tcpEstablishedTimeout,omitempty"`
+	TCPCloseWaitTimeout   string `json:"tcpCloseWaitTimeout,omitempty"`
+}
+
+// KubeProxyIPVSConfig holds the IPVS settings of kube-proxy
+type KubeProxyIPVSConfig struct {
+	// Example: rr
+	Scheduler string `json:"scheduler"`
+	StrictARP bool   `json:"strictARP"`
+}
+
+// kubeProxyConfiguration is the subset of KubeProxyConfiguration which is of interest
+type kubeProxyConfiguration struct {
+	ClientConnection struct {
+		Kubeconfig string `json:"kubeconfig"`
+	} `json:"clientConnection"`
+	Mode               string `json:"mode"`
+	ClusterCIDR        string `json:"clusterCIDR"`
+	MetricsBindAddress string `json:"metricsBindAddress"`
+	HealthzBindAddress string `json:"healthzBindAddress"`
+	Conntrack          struct {
+		MaxPerCore            *int32 `json:"maxPerCore"`
+		Min                   *int32 `json:"min"`
+		TCPEstablishedTimeout string `json:"tcpEstablishedTimeout"`
+		TCPCloseWaitTimeout   string `json:"tcpCloseWaitTimeout"`
+	} `json:"conntrack"`
+	IPVS struct {
+		Scheduler string `json:"scheduler"`
+		StrictARP *bool  `json:"strictARP"`
+	} `json:"ipvs"`
+}
+
+// parseKubeProxyConfig parses a KubeProxyConfiguration file (yaml or json)
+func parseKubeProxyConfig(content []byte) (*kubeProxyConfiguration, error) {
+	conf := kubeProxyConfiguration{}
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal kube-proxy config: %w", err)
+	}
+	return &conf, nil
+}
+
+// isBindAllInterfaces returns true if `address` (host or host:port) listens on all interfaces
+func isBindAllInterfaces(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// kubeProxyEffectiveConfig resolves the effective configuration of kube-proxy from `fileConf`, the parsed config file.
+// As kube-proxy does, when a config file is used the flags of these settings are ignored, and the settings which
+// are not set in the file have their defaults. Without a config file, the flags take precedence over the defaults.
+func kubeProxyEffectiveConfig(proc *utils.ProcessDetails, fileConf *kubeProxyConfiguration) *KubeProxyConfig {
+	ret := KubeProxyConfig{Source: kubeProxyConfigSourceDefault}
+	useFile := fileConf != nil
+	usedFlags, usedFile := false, false
+
+	// pick returns the config file value, or the flag value without a config file, or the default
+	pick := func(fileVal, argName, defaultVal string) string {
+		if useFile {
+			if fileVal != "" {
+				usedFile = true
+				return fileVal
+			}
+			return defaultVal
+		}
+		if val, ok := proc.GetArg(argName); ok && val != "" {
+			usedFlags = true
+			return val
+		}
+		return defaultVal
+	}
+	pickInt32 := func(fileVal *int32, argName string, defaultVal int32) *int32 {
+		if useFile {
+			if fileVal != nil {
+				usedFile = true
+				return fileVal
+			}
+			return &defaultVal
+		}
+		if val, ok := proc.GetArg(argName); ok {
+			if parsed, err := strconv.ParseInt(val, 10, 32); err == nil {
+				usedFlags = true
+				res := int32(parsed)
+				return &res
+			}
+		}
+		return &defaultVal
+	}
+
+	if fileConf == nil {
+		fileConf = &kubeProxyConfiguration{}
+	}
+	ret.Mode = pick(fileConf.Mode, kubeProxyModeArgName, kubeProxyDefaultMode)
+	ret.ClusterCIDR = pick(fileConf.ClusterCIDR, kubeProxyClusterCIDRArgName, "")
+	ret.MetricsBindAddress = pick(fileConf.MetricsBindAddress, kubeProxyMetricsBindAddressArgName, kubeProxyDefaultMetricsBindAddress)
+	ret.MetricsBindAllInterfaces = isBindAllInterfaces(ret.MetricsBindAddress)
+	ret.HealthzBindAddress = pick(fileConf.HealthzBindAddress, kubeProxyHealthzBindAddressArgName, kubeProxyDefaultHealthzBindAddress)
+	ret.HealthzBindAllInterfaces = isBindAllInterfaces(ret.HealthzBindAddress)
+
+	ret.Conntrack.MaxPerCore = pickInt32(fileConf.Conntrack.MaxPerCore, kubeProxyConntrackMaxPerCoreArgName, kubeProxyDefaultConntrackMaxPerCore)
+	ret.Conntrack.Min = pickInt32(fileConf.Conntrack.Min, kubeProxyConntrackMinArgName, kubeProxyDefaultConntrackMin)
+	ret.Conntrack.TCPEstablishedTimeout = pick(fileConf.Conntrack.TCPEstablishedTimeout,
+		kubeProxyConntrackEstablishedArgName, kubeProxyDefaultConntrackEstablished)
+	ret.Conntrack.TCPCloseWaitTimeout = pick(fileConf.Conntrack.TCPCloseWaitTimeout,
+		kubeProxyConntrackCloseWaitArgName, kubeProxyDefaultConntrackCloseWait)
+
+	if ret.Mode == KubeProxyModeIPVS {
+		ret.IPVS = &KubeProxyIPVSConfig{
+			Scheduler: pick(fileConf.IPVS.Scheduler, kubeProxyIPVSSchedulerArgName, kubeProxyDefaultIPVSScheduler),
+		}
+		if useFile {
+			if fileConf.IPVS.StrictARP != nil {
+				usedFile = true
+				ret.IPVS.StrictARP = *fileConf.IPVS.StrictARP
+			}
+		} else if val, ok := proc.GetArg(kubeProxyIPVSStrictARPArgName); ok {
+			usedFlags = true
+			ret.IPVS.StrictARP, _ = strconv.ParseBool(val)
+		}
+	}
+
+	switch {
+	case usedFile:
+		ret.Source = kubeProxyConfigSourceConfigFile
+	case usedFlags:
+		ret.Source = kubeProxyConfigSourceFlag
+	}
+
+	return &ret
+}
+
 // SenseKubeProxyInfo return `KubeProxyInfo`
 func SenseKubeProxyInfo(ctx context.Context) (*KubeProxyInfo, error) {
 	ret := KubeProxyInfo{}
@@ -32,8 +243,27 @@ func SenseKubeProxyInfo(ctx context.Context) (*KubeProxyInfo, error) {
 		return &ret, fmt.Errorf("failed to locate kube-proxy process: %w", err)
 	}
 
+	// config file
+	var fileConf *kubeProxyConfiguration
+	configPath, ok := proc.GetArg(kubeProxyConfigArgName)
+	if ok {
+		ret.ConfigFile = makeContaineredFileInfoVerbose(ctx, proc, configPath, true,
+			helpers.String("in", "SenseKubeProxyInfo"),
+		)
+	}
+	if ret.ConfigFile != nil && ret.ConfigFile.Content != nil {
+		fileConf, err = parseKubeProxyConfig(ret.ConfigFile.Content)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("In SenseKubeProxyInfo failed to parse config file", helpers.Error(err))
+		}
+	}
+	ret.Config = kubeProxyEffectiveConfig(proc, fileConf)
+
 	// kubeconfig
 	kubeConfigPath, ok := proc.GetArg(kubeConfigArgName)
+	if fileConf != nil && fileConf.ClientConnection.Kubeconfig != "" {
+		kubeConfigPath, ok = fileConf.ClientConnection.Kubeconfig, true
+	}
 	if ok {
 		ret.KubeConfigFile = makeContaineredFileInfoVerbose(ctx, proc, kubeConfigPath, false,
 			helpers.String("in", "SenseKubeProxyInfo"),
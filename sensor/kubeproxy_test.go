@@ -0,0 +1,110 @@
+package sensor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isBindAllInterfaces(t *testing.T) {
+	tests := map[string]bool{
+		"0.0.0.0:10249":   true,
+		":10249":          true,
+		"[::]:10256":      true,
+		"0.0.0.0":         true,
+		"127.0.0.1:10249": false,
+		"[::1]:10249":     false,
+		"10.0.0.5":        false,
+	}
+	for address, want := range tests {
+		assert.Equal(t, want, isBindAllInterfaces(address), address)
+	}
+}
+
+func Test_kubeProxyEffectiveConfig(t *testing.T) {
+	content, err := os.ReadFile("testdata/kubeProxyConfig.yaml")
+	require.NoError(t, err)
+	fileConf, err := parseKubeProxyConfig(content)
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/kube-proxy/kubeconfig.conf", fileConf.ClientConnection.Kubeconfig)
+
+	maxPerCore, conntrackMin := int32(kubeProxyDefaultConntrackMaxPerCore), int32(kubeProxyDefaultConntrackMin)
+	flagsMaxPerCore := int32(0)
+
+	tests := []struct {
+		name     string
+		cmdLine  []string
+		fileConf *kubeProxyConfiguration
+		want     *KubeProxyConfig
+	}{
+		{
+			name:    "defaults",
+			cmdLine: []string{"/usr/local/bin/kube-proxy"},
+			want: &KubeProxyConfig{
+				Source:                   kubeProxyConfigSourceDefault,
+				Mode:                     "iptables",
+				MetricsBindAddress:       "127.0.0.1:10249",
+				HealthzBindAddress:       "0.0.0.0:10256",
+				HealthzBindAllInterfaces: true,
+				Conntrack: KubeProxyConntrackConfig{
+					MaxPerCore:            &maxPerCore,
+					Min:                   &conntrackMin,
+					TCPEstablishedTimeout: "24h0m0s",
+					TCPCloseWaitTimeout:   "1h0m0s",
+				},
+			},
+		},
+		{
+			name: "flags",
+			cmdLine: []string{"/usr/local/bin/kube-proxy", "--proxy-mode=nftables", "--cluster-cidr", "10.200.0.0/16",
+				"--healthz-bind-address=127.0.0.1:10256", "--conntrack-max-per-core=0"},
+			want: &KubeProxyConfig{
+				Source:             kubeProxyConfigSourceFlag,
+				Mode:               "nftables",
+				ClusterCIDR:        "10.200.0.0/16",
+				MetricsBindAddress: "127.0.0.1:10249",
+				HealthzBindAddress: "127.0.0.1:10256",
+				Conntrack: KubeProxyConntrackConfig{
+					MaxPerCore:            &flagsMaxPerCore,
+					Min:                   &conntrackMin,
+					TCPEstablishedTimeout: "24h0m0s",
+					TCPCloseWaitTimeout:   "1h0m0s",
+				},
+			},
+		},
+		{
+			name:     "config file overrides flags",
+			cmdLine:  []string{"/usr/local/bin/kube-proxy", "--config=/var/lib/kube-proxy/config.conf", "--proxy-mode=iptables", "--ipvs-scheduler=wrr"},
+			fileConf: fileConf,
+			want: &KubeProxyConfig{
+				Source:                   kubeProxyConfigSourceConfigFile,
+				Mode:                     "ipvs",
+				ClusterCIDR:              "10.244.0.0/16",
+				MetricsBindAddress:       "0.0.0.0:10249",
+				MetricsBindAllInterfaces: true,
+				HealthzBindAddress:       "0.0.0.0:10256",
+				HealthzBindAllInterfaces: true,
+				Conntrack: KubeProxyConntrackConfig{
+					MaxPerCore:            &maxPerCore,
+					Min:                   &conntrackMin,
+					TCPEstablishedTimeout: "24h0m0s",
+					TCPCloseWaitTimeout:   "1h0m0s",
+				},
+				// the scheduler is not set in the file, so --ipvs-scheduler is ignored
+				IPVS: &KubeProxyIPVSConfig{Scheduler: "rr", StrictARP: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc := &utils.ProcessDetails{CmdLine: tt.cmdLine, PID: 1}
+			assert.Equal(t, tt.want, kubeProxyEffectiveConfig(proc, tt.fileConf))
+		})
+	}
+
+	_, err = parseKubeProxyConfig([]byte("mode: [ipvs"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,351 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	fstabFileName = "/etc/fstab"
+	kubeletDir    = "/var/lib/kubelet"
+
+	// mount propagation types
+	MountPropagationShared     = "shared"
+	MountPropagationSlave      = "slave"
+	MountPropagationPrivate    = "private"
+	MountPropagationUnbindable = "unbindable"
+)
+
+// mountControl is a CIS control of a mount point: either a separate partition, or a mount option
+type mountControl struct {
+	mountPoint string
+
+	// the required mount option, empty for a separate partition control
+	option string
+}
+
+var cisMountControls = []mountControl{
+	{mountPoint: "/tmp"},
+	{mountPoint: "/tmp", option: "nodev"},
+	{mountPoint: "/tmp", option: "nosuid"},
+	{mountPoint: "/tmp", option: "noexec"},
+	{mountPoint: "/dev/shm", option: "nodev"},
+	{mountPoint: "/dev/shm", option: "nosuid"},
+	{mountPoint: "/dev/shm", option: "noexec"},
+	{mountPoint: "/var"},
+	{mountPoint: "/var/log"},
+}
+
+// Mount holds an entry of /proc/<pid>/mountinfo
+type Mount struct {
+	MountID  int `json:"mountID"`
+	ParentID int `json:"parentID"`
+
+	// major:minor of the mounted file system
+	Device string `json:"device"`
+
+	// The mounted dir of the file system, `/` unless it is a bind mount of a sub dir
+	Root string `json:"root"`
+
+	MountPoint string `json:"mountPoint"`
+
+	// Example: /dev/sda1
+	Source string `json:"source"`
+
+	// Example: ext4
+	FSType string `json:"fsType"`
+
+	// shared, slave, private or unbindable
+	Propagation string `json:"propagation"`
+
+	// The per mount options
+	// Example: ["rw", "nosuid", "nodev", "relatime"]
+	Options []string `json:"options"`
+
+	// The per file system options
+	SuperOptions []string `json:"superOptions"`
+}
+
+// FstabEntry holds an entry of /etc/fstab, and whether it is mounted as configured
+type FstabEntry struct {
+	// Example: UUID=3f1c0b2e-7a9d-4c1e-8f2a-6b5d4e3c2a10
+	Spec       string   `json:"spec"`
+	MountPoint string   `json:"mountPoint"`
+	FSType     string   `json:"fsType"`
+	Options    []string `json:"options"`
+
+	Mounted bool `json:"mounted"`
+
+	// The nodev, nosuid, noexec and ro options of the entry which are missing from the mount
+	MissingOptions []string `json:"missingOptions,omitempty"`
+}
+
+// MountControlResult holds the result of a CIS mount control
+type MountControlResult struct {
+	// Example: /tmp nodev
+	Control string `json:"control"`
+	Passed  bool   `json:"passed"`
+}
+
+// HostRootBindMount holds a bind mount of the host root file system in another mount namespace
+type HostRootBindMount struct {
+	// The PIDs of the processes of the mount namespace
+	PIDs []int32 `json:"pids"`
+
+	MountPoint string   `json:"mountPoint"`
+	Options    []string `json:"options"`
+}
+
+// MountsInfo holds the mount table of the host and its hardening controls
+type MountsInfo struct {
+	Mounts   []Mount              `json:"mounts"`
+	Fstab    []FstabEntry         `json:"fstab"`
+	Controls []MountControlResult `json:"controls"`
+
+	// The propagation of the mount holding /var/lib/kubelet
+	KubeletDirPropagation string `json:"kubeletDirPropagation,omitempty"`
+
+	// true if the mount holding /var/lib/kubelet has shared propagation
+	KubeletDirShared bool `json:"kubeletDirShared"`
+
+	// Read-write bind mounts of the host root into containers (the scanner's own mount is excluded)
+	HostRootBindMounts []HostRootBindMount `json:"hostRootBindMounts"`
+}
+
+// unescapeMountField replaces the octal escapes of mountinfo and fstab fields, e.g. `\040` for a space
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var res strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if val, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				res.WriteByte(byte(val))
+				i += 3
+				continue
+			}
+		}
+		res.WriteByte(field[i])
+	}
+	return res.String()
+}
+
+// parseMountInfo parses the content of /proc/<pid>/mountinfo.
+// Example line: `36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue`
+func parseMountInfo(content []byte) ([]Mount, error) {
+	res := make([]Mount, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		separator := slices.Index(fields, "-")
+		if separator < 6 || len(fields) < separator+4 {
+			return nil, fmt.Errorf("invalid mountinfo line: %q", scanner.Text())
+		}
+		mount := Mount{
+			Device:       fields[2],
+			Root:         unescapeMountField(fields[3]),
+			MountPoint:   unescapeMountField(fields[4]),
+			Options:      strings.Split(fields[5], ","),
+			Propagation:  MountPropagationPrivate,
+			FSType:       fields[separator+1],
+			Source:       unescapeMountField(fields[separator+2]),
+			SuperOptions: strings.Split(fields[separator+3], ","),
+		}
+		mount.MountID, _ = strconv.Atoi(fields[0])
+		mount.ParentID, _ = strconv.Atoi(fields[1])
+		for _, optional := range fields[6:separator] {
+			switch {
+			case strings.HasPrefix(optional, "shared:"):
+				mount.Propagation = MountPropagationShared
+			case strings.HasPrefix(optional, "master:") && mount.Propagation != MountPropagationShared:
+				mount.Propagation = MountPropagationSlave
+			case optional == "unbindable":
+				mount.Propagation = MountPropagationUnbindable
+			}
+		}
+		res = append(res, mount)
+	}
+	return res, nil
+}
+
+// parseFstab parses the content of /etc/fstab. Swap entries are skipped.
+func parseFstab(content []byte) []FstabEntry {
+	res := make([]FstabEntry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] == "swap" || !strings.HasPrefix(fields[1], "/") {
+			continue
+		}
+		entry := FstabEntry{
+			Spec:       unescapeMountField(fields[0]),
+			MountPoint: path.Clean(unescapeMountField(fields[1])),
+			FSType:     fields[2],
+			Options:    []string{"defaults"},
+		}
+		if len(fields) > 3 {
+			entry.Options = strings.Split(fields[3], ",")
+		}
+		res = append(res, entry)
+	}
+	return res
+}
+
+// findMount returns the last mount on `mountPoint`, which hides the former ones
+func findMount(mounts []Mount, mountPoint string) *Mount {
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if mounts[i].MountPoint == mountPoint {
+			return &mounts[i]
+		}
+	}
+	return nil
+}
+
+// findHoldingMount returns the mount which holds `dirPath`, i.e. the last mount with the longest matching mount point
+func findHoldingMount(mounts []Mount, dirPath string) *Mount {
+	var res *Mount
+	for i := range mounts {
+		mountPoint := mounts[i].MountPoint
+		if mountPoint != "/" && mountPoint != dirPath && !strings.HasPrefix(dirPath, mountPoint+"/") {
+			continue
+		}
+		if res == nil || len(mountPoint) >= len(res.MountPoint) {
+			res = &mounts[i]
+		}
+	}
+	return res
+}
+
+// checkFstab marks the fstab entries which are mounted, and the hardening options they miss
+func checkFstab(entries []FstabEntry, mounts []Mount) {
+	for i := range entries {
+		mount := findMount(mounts, entries[i].MountPoint)
+		if mount == nil {
+			continue
+		}
+		entries[i].Mounted = true
+		for _, option := range []string{"nodev", "nosuid", "noexec", "ro"} {
+			if slices.Contains(entries[i].Options, option) && !slices.Contains(mount.Options, option) {
+				entries[i].MissingOptions = append(entries[i].MissingOptions, option)
+			}
+		}
+	}
+}
+
+// checkMountControls returns the results of the CIS mount controls
+func checkMountControls(mounts []Mount) []MountControlResult {
+	res := make([]MountControlResult, 0, len(cisMountControls))
+	for _, control := range cisMountControls {
+		mount := findMount(mounts, control.mountPoint)
+		if control.option == "" {
+			res = append(res, MountControlResult{Control: control.mountPoint + " separate partition", Passed: mount != nil})
+			continue
+		}
+		res = append(res, MountControlResult{
+			Control: control.mountPoint + " " + control.option,
+			Passed:  mount != nil && slices.Contains(mount.Options, control.option),
+		})
+	}
+	return res
+}
+
+// findHostRootBindMounts returns the read-write mounts of the host root file system `hostRoot` in the mount
+// namespaces under `procDir`, other than the host and the `excludedMntNs` namespaces
+func findHostRootBindMounts(ctx context.Context, procDir string, hostRoot *Mount, excludedMntNs ...string) []HostRootBindMount {
+	res := make([]HostRootBindMount, 0)
+	namespaces, err := listNamespaces(procDir, "mnt")
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseMounts failed to list mount namespaces", helpers.Error(err))
+		return res
+	}
+	hostMntNs, _ := os.Readlink(path.Join(procDir, "1", "ns", "mnt"))
+
+	nsNames := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		if ns != hostMntNs && !slices.Contains(excludedMntNs, ns) {
+			nsNames = append(nsNames, ns)
+		}
+	}
+	sort.Strings(nsNames)
+
+	for _, ns := range nsNames {
+		pids := namespaces[ns]
+		content, err := os.ReadFile(path.Join(procDir, strconv.Itoa(int(pids[0])), "mountinfo"))
+		if err != nil {
+			continue
+		}
+		mounts, err := parseMountInfo(content)
+		if err != nil {
+			continue
+		}
+		for _, mount := range mounts {
+			if mount.Device != hostRoot.Device || mount.Root != hostRoot.Root || !slices.Contains(mount.Options, "rw") {
+				continue
+			}
+			res = append(res, HostRootBindMount{PIDs: pids, MountPoint: mount.MountPoint, Options: mount.Options})
+		}
+	}
+	return res
+}
+
+// senseMounts reads the mount table of process 1 under `procDir`, and the fstab of the host file system at `rootDir`
+func senseMounts(ctx context.Context, rootDir, procDir string, excludedMntNs ...string) (*MountsInfo, error) {
+	ret := MountsInfo{
+		Mounts:             make([]Mount, 0),
+		Fstab:              make([]FstabEntry, 0),
+		HostRootBindMounts: make([]HostRootBindMount, 0),
+	}
+
+	content, err := os.ReadFile(path.Join(procDir, "1", "mountinfo"))
+	if err != nil {
+		return &ret, err
+	}
+	if ret.Mounts, err = parseMountInfo(content); err != nil {
+		return &ret, err
+	}
+
+	if fstab, err := os.ReadFile(path.Join(rootDir, fstabFileName)); err == nil {
+		ret.Fstab = parseFstab(fstab)
+		checkFstab(ret.Fstab, ret.Mounts)
+	} else {
+		logger.L().Ctx(ctx).Debug("In SenseMounts failed to read fstab", helpers.Error(err))
+	}
+
+	ret.Controls = checkMountControls(ret.Mounts)
+
+	if kubeletMount := findHoldingMount(ret.Mounts, kubeletDir); kubeletMount != nil {
+		ret.KubeletDirPropagation = kubeletMount.Propagation
+		ret.KubeletDirShared = kubeletMount.Propagation == MountPropagationShared
+	}
+
+	if hostRoot := findMount(ret.Mounts, "/"); hostRoot != nil {
+		ret.HostRootBindMounts = findHostRootBindMounts(ctx, procDir, hostRoot, excludedMntNs...)
+	}
+
+	return &ret, nil
+}
+
+// SenseMounts returns the mount table of the host compared with /etc/fstab, the CIS mount controls,
+// the propagation of /var/lib/kubelet and the read-write bind mounts of the host root into containers
+func SenseMounts(ctx context.Context) (*MountsInfo, error) {
+	// the scanner mounts the host root itself
+	selfMntNs, _ := os.Readlink(path.Join(procDirName, "self", "ns", "mnt"))
+	return senseMounts(ctx, utils.HostFileSystemDefaultLocation, procDirName, selfMntNs)
+}
@@ -0,0 +1,61 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseMountInfo(t *testing.T) {
+	mounts, err := parseMountInfo([]byte("36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue\n" +
+		"37 35 98:0 / /mnt\\040x rw shared:2 master:1 - ext3 /dev/root rw\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Mount{
+		MountID: 36, ParentID: 35, Device: "98:0", Root: "/mnt1", MountPoint: "/mnt2", Source: "/dev/root", FSType: "ext3",
+		Propagation: MountPropagationSlave, Options: []string{"rw", "noatime"}, SuperOptions: []string{"rw", "errors=continue"},
+	}, mounts[0])
+	assert.Equal(t, "/mnt x", mounts[1].MountPoint)
+	assert.Equal(t, MountPropagationShared, mounts[1].Propagation)
+
+	_, err = parseMountInfo([]byte("36 35 98:0 /mnt1 /mnt2\n"))
+	assert.Error(t, err)
+}
+
+func Test_senseMounts(t *testing.T) {
+	info, err := senseMounts(context.TODO(), "testdata/mounts/root", "testdata/mounts/proc", "mnt:[4026532400]")
+	require.NoError(t, err)
+	assert.Len(t, info.Mounts, 7)
+
+	require.Len(t, info.Fstab, 5)
+	assert.True(t, info.Fstab[0].Mounted)
+	assert.Equal(t, FstabEntry{
+		Spec: "tmpfs", MountPoint: "/tmp", FSType: "tmpfs", Options: []string{"defaults", "nodev", "nosuid", "noexec"},
+		Mounted: true, MissingOptions: []string{"noexec"},
+	}, info.Fstab[2])
+	assert.True(t, info.Fstab[3].Mounted)
+	assert.Equal(t, "/srv", info.Fstab[4].MountPoint)
+	assert.False(t, info.Fstab[4].Mounted)
+
+	assert.Equal(t, []MountControlResult{
+		{Control: "/tmp separate partition", Passed: true},
+		{Control: "/tmp nodev", Passed: true},
+		{Control: "/tmp nosuid", Passed: true},
+		{Control: "/tmp noexec", Passed: false},
+		{Control: "/dev/shm nodev", Passed: true},
+		{Control: "/dev/shm nosuid", Passed: true},
+		{Control: "/dev/shm noexec", Passed: false},
+		{Control: "/var separate partition", Passed: false},
+		{Control: "/var/log separate partition", Passed: true},
+	}, info.Controls)
+
+	// /var/lib/kubelet is held by the root mount
+	assert.Equal(t, MountPropagationShared, info.KubeletDirPropagation)
+	assert.True(t, info.KubeletDirShared)
+
+	// the read-only bind of 702 and the excluded namespace of 701 are not reported
+	assert.Equal(t, []HostRootBindMount{
+		{PIDs: []int32{700}, MountPoint: "/host", Options: []string{"rw", "relatime"}},
+	}, info.HostRootBindMounts)
+}
@@ -0,0 +1,434 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	sysClassNetDir = "/sys/class/net"
+	procSysNetDir  = "/proc/sys/net"
+
+	// paths relative to `/proc/<pid>`
+	procNetRoutePath     = "net/route"
+	procNetIPv6RoutePath = "net/ipv6_route"
+	procNetIfInet6Path   = "net/if_inet6"
+
+	// net_device flags
+	iffUp      = 0x1
+	iffPromisc = 0x100
+
+	// route flags
+	rtfUp      = 0x1
+	rtfGateway = 0x2
+	rtfReject  = 0x200
+	rtfLocal   = 0x80000000
+
+	RouteFamilyIPv4 = "ipv4"
+	RouteFamilyIPv6 = "ipv6"
+)
+
+// Kinds of interfaces without a DEVTYPE, by their ARPHRD type
+var netInterfaceTypeKinds = map[int]string{
+	768:   "ipip",
+	769:   "ip6tnl",
+	772:   "loopback",
+	776:   "sit",
+	778:   "gre",
+	823:   "ip6gre",
+	65534: "none",
+}
+
+// NetworkInterface holds information about a network interface of the host
+type NetworkInterface struct {
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+
+	// The interface kind, as reported by the kernel or deduced from its attributes.
+	// Example: device, loopback, bridge, veth, vxlan, tun, wireguard
+	Kind string `json:"kind"`
+
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// Example: up, down, unknown
+	OperState string `json:"operState"`
+
+	MTU         int  `json:"mtu"`
+	Up          bool `json:"up"`
+	Promiscuous bool `json:"promiscuous"`
+
+	// The interface this interface is attached to (e.g. the bridge of a veth)
+	Master string `json:"master,omitempty"`
+
+	// Addresses in CIDR notation
+	Addresses []string `json:"addresses"`
+
+	// Values of net.ipv4.conf.<name>.forwarding, net.ipv6.conf.<name>.forwarding and net.ipv4.conf.<name>.rp_filter
+	IPv4Forwarding *int `json:"ipv4Forwarding,omitempty"`
+	IPv6Forwarding *int `json:"ipv6Forwarding,omitempty"`
+	RPFilter       *int `json:"rpFilter,omitempty"`
+}
+
+// Route holds a routing table entry of the host
+type Route struct {
+	// ipv4 or ipv6
+	Family    string `json:"family"`
+	Interface string `json:"interface"`
+
+	// Example: 10.244.0.0/24
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway,omitempty"`
+	Metric      uint32 `json:"metric"`
+}
+
+// NetworkInfo holds the network interfaces, routes and forwarding state of the host network namespace
+type NetworkInfo struct {
+	Interfaces    []NetworkInterface `json:"interfaces"`
+	Routes        []Route            `json:"routes"`
+	DefaultRoutes []Route            `json:"defaultRoutes"`
+
+	// Names of the interfaces in promiscuous mode
+	PromiscuousInterfaces []string `json:"promiscuousInterfaces"`
+
+	// Values of net.ipv4.ip_forward, net.ipv6.conf.all.forwarding and net.ipv4.conf.all.rp_filter
+	IPv4Forwarding *int `json:"ipv4Forwarding,omitempty"`
+	IPv6Forwarding *int `json:"ipv6Forwarding,omitempty"`
+	RPFilter       *int `json:"rpFilter,omitempty"`
+}
+
+// readSysFile returns the trimmed content of a sysfs (or procfs) attribute
+func readSysFile(elem ...string) (string, error) {
+	content, err := os.ReadFile(path.Join(elem...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// readSysInt returns the value of an integer sysfs (or procfs) attribute, nil if missing
+func readSysInt(elem ...string) *int {
+	content, err := readSysFile(elem...)
+	if err != nil {
+		return nil
+	}
+	val, err := strconv.ParseInt(content, 0, 64)
+	if err != nil {
+		return nil
+	}
+	res := int(val)
+	return &res
+}
+
+// netInterfaceKind deduces the kind of the interface at `ifaceDir`
+func netInterfaceKind(ifaceDir string, iface *NetworkInterface) string {
+	if uevent, err := readSysFile(ifaceDir, "uevent"); err == nil {
+		for _, line := range strings.Split(uevent, "\n") {
+			if devType, ok := strings.CutPrefix(line, "DEVTYPE="); ok {
+				return devType
+			}
+		}
+	}
+	if _, err := os.Lstat(path.Join(ifaceDir, "tun_flags")); err == nil {
+		return "tun"
+	}
+	if typ := readSysInt(ifaceDir, "type"); typ != nil {
+		if kind, ok := netInterfaceTypeKinds[*typ]; ok {
+			return kind
+		}
+	}
+	if _, err := os.Lstat(path.Join(ifaceDir, "device")); err == nil {
+		return "device"
+	}
+	// a virtual ethernet interface linked to another interface is a veth peer
+	if ifLink := readSysInt(ifaceDir, "iflink"); ifLink != nil && *ifLink != iface.Index {
+		return "veth"
+	}
+	return "virtual"
+}
+
+// readSysClassNet reads the network interfaces under `sysClassNet` (e.g. /sys/class/net)
+func readSysClassNet(sysClassNet string) ([]NetworkInterface, error) {
+	entries, err := os.ReadDir(sysClassNet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network interfaces dir: %w", err)
+	}
+
+	res := make([]NetworkInterface, 0, len(entries))
+	for _, entry := range entries {
+		ifaceDir := path.Join(sysClassNet, entry.Name())
+		// skip files such as `bonding_masters`
+		if info, err := os.Stat(ifaceDir); err != nil || !info.IsDir() {
+			continue
+		}
+		iface := NetworkInterface{Name: entry.Name(), Addresses: []string{}}
+		if index := readSysInt(ifaceDir, "ifindex"); index != nil {
+			iface.Index = *index
+		}
+		if mtu := readSysInt(ifaceDir, "mtu"); mtu != nil {
+			iface.MTU = *mtu
+		}
+		if flags := readSysInt(ifaceDir, "flags"); flags != nil {
+			iface.Up = *flags&iffUp != 0
+			iface.Promiscuous = *flags&iffPromisc != 0
+		}
+		iface.OperState, _ = readSysFile(ifaceDir, "operstate")
+		if mac, _ := readSysFile(ifaceDir, "address"); mac != "" && mac != "00:00:00:00:00:00" {
+			iface.MACAddress = mac
+		}
+		if master, err := os.Readlink(path.Join(ifaceDir, "master")); err == nil {
+			iface.Master = path.Base(master)
+		}
+		iface.Kind = netInterfaceKind(ifaceDir, &iface)
+		res = append(res, iface)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Index < res[j].Index })
+	return res, nil
+}
+
+// parseHexIPv4 parses an IPv4 address of /proc/net/route, which is printed in host byte order
+func parseHexIPv4(s string) (net.IP, error) {
+	val, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.NativeEndian.PutUint32(ip, uint32(val))
+	return ip, nil
+}
+
+// parseHexIPv6 parses an IPv6 address of /proc/net/ipv6_route and /proc/net/if_inet6
+func parseHexIPv6(s string) (net.IP, error) {
+	ip, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(ip) != net.IPv6len {
+		return nil, fmt.Errorf("invalid IPv6 address %q", s)
+	}
+	return ip, nil
+}
+
+// parseProcNetRoute parses the content of /proc/net/route and returns the active routes
+func parseProcNetRoute(content []byte) []Route {
+	res := make([]Route, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&rtfUp == 0 || flags&rtfReject != 0 {
+			continue
+		}
+		dst, err := parseHexIPv4(fields[1])
+		if err != nil {
+			continue
+		}
+		mask, err := parseHexIPv4(fields[7])
+		if err != nil {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[6], 10, 32)
+		route := Route{
+			Family:      RouteFamilyIPv4,
+			Interface:   fields[0],
+			Destination: (&net.IPNet{IP: dst, Mask: net.IPMask(mask)}).String(),
+			Metric:      uint32(metric),
+		}
+		if flags&rtfGateway != 0 {
+			if gw, err := parseHexIPv4(fields[2]); err == nil {
+				route.Gateway = gw.String()
+			}
+		}
+		res = append(res, route)
+	}
+	return res
+}
+
+// parseProcNetIPv6Route parses the content of /proc/net/ipv6_route and returns the active routes.
+// Local and unreachable routes are omitted.
+func parseProcNetIPv6Route(content []byte) []Route {
+	res := make([]Route, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// dst dst_len src src_len gateway metric refcnt use flags iface
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[8], 16, 32)
+		if err != nil || flags&rtfUp == 0 || flags&(rtfReject|rtfLocal) != 0 {
+			continue
+		}
+		dst, err := parseHexIPv6(fields[0])
+		if err != nil {
+			continue
+		}
+		prefixLen, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil {
+			continue
+		}
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+		route := Route{
+			Family:      RouteFamilyIPv6,
+			Interface:   fields[9],
+			Destination: (&net.IPNet{IP: dst, Mask: net.CIDRMask(int(prefixLen), 128)}).String(),
+			Metric:      uint32(metric),
+		}
+		if flags&rtfGateway != 0 {
+			if gw, err := parseHexIPv6(fields[4]); err == nil {
+				route.Gateway = gw.String()
+			}
+		}
+		res = append(res, route)
+	}
+	return res
+}
+
+// parseProcNetIfInet6 parses the content of /proc/net/if_inet6 and returns the IPv6 addresses of each interface
+func parseProcNetIfInet6(content []byte) map[string][]string {
+	res := map[string][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// address ifindex prefix_len scope flags name
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		ip, err := parseHexIPv6(fields[0])
+		if err != nil {
+			continue
+		}
+		prefixLen, err := strconv.ParseUint(fields[2], 16, 8)
+		if err != nil {
+			continue
+		}
+		res[fields[5]] = append(res[fields[5]], fmt.Sprintf("%s/%d", ip, prefixLen))
+	}
+	return res
+}
+
+// isDefaultRoute returns true if the route matches all destinations
+func isDefaultRoute(route *Route) bool {
+	return route.Destination == "0.0.0.0/0" || route.Destination == "::/0"
+}
+
+// buildNetworkInfo builds the network information from the interfaces of `sysClassNet`
+// and the routing tables of the network namespace of `procPidDir` (e.g. /proc/1)
+func buildNetworkInfo(ctx context.Context, sysClassNet, procPidDir string) (*NetworkInfo, error) {
+	ifaces, err := readSysClassNet(sysClassNet)
+	if err != nil {
+		return nil, err
+	}
+	ret := NetworkInfo{
+		Interfaces:            ifaces,
+		Routes:                make([]Route, 0),
+		DefaultRoutes:         make([]Route, 0),
+		PromiscuousInterfaces: make([]string, 0),
+	}
+
+	if content, err := os.ReadFile(path.Join(procPidDir, procNetRoutePath)); err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseNetworkInterfaces failed to read IPv4 routes", helpers.Error(err))
+	} else {
+		ret.Routes = append(ret.Routes, parseProcNetRoute(content)...)
+	}
+	// IPv6 might be disabled
+	if content, err := os.ReadFile(path.Join(procPidDir, procNetIPv6RoutePath)); err == nil {
+		ret.Routes = append(ret.Routes, parseProcNetIPv6Route(content)...)
+	}
+	for i := range ret.Routes {
+		if isDefaultRoute(&ret.Routes[i]) {
+			ret.DefaultRoutes = append(ret.DefaultRoutes, ret.Routes[i])
+		}
+	}
+
+	var ipv6Addrs map[string][]string
+	if content, err := os.ReadFile(path.Join(procPidDir, procNetIfInet6Path)); err == nil {
+		ipv6Addrs = parseProcNetIfInet6(content)
+	}
+	for i := range ret.Interfaces {
+		iface := &ret.Interfaces[i]
+		iface.Addresses = append(iface.Addresses, ipv6Addrs[iface.Name]...)
+		if iface.Promiscuous {
+			ret.PromiscuousInterfaces = append(ret.PromiscuousInterfaces, iface.Name)
+		}
+	}
+
+	return &ret, nil
+}
+
+// readNetSysctls fills the forwarding and rp_filter values from `procSysNet` (e.g. /proc/sys/net).
+// The values are of the network namespace of the reading thread.
+func readNetSysctls(info *NetworkInfo, procSysNet string) {
+	info.IPv4Forwarding = readSysInt(procSysNet, "ipv4", "ip_forward")
+	info.IPv6Forwarding = readSysInt(procSysNet, "ipv6", "conf", "all", "forwarding")
+	info.RPFilter = readSysInt(procSysNet, "ipv4", "conf", "all", "rp_filter")
+	for i := range info.Interfaces {
+		iface := &info.Interfaces[i]
+		iface.IPv4Forwarding = readSysInt(procSysNet, "ipv4", "conf", iface.Name, "forwarding")
+		iface.IPv6Forwarding = readSysInt(procSysNet, "ipv6", "conf", iface.Name, "forwarding")
+		iface.RPFilter = readSysInt(procSysNet, "ipv4", "conf", iface.Name, "rp_filter")
+	}
+}
+
+// addIPv4Addresses adds the IPv4 addresses of the interfaces, which are not exposed in sysfs nor procfs.
+// The addresses are of the network namespace of the calling thread.
+func addIPv4Addresses(info *NetworkInfo) error {
+	for i := range info.Interfaces {
+		iface := &info.Interfaces[i]
+		netIface, err := net.InterfaceByName(iface.Name)
+		if err != nil {
+			continue
+		}
+		addrs, err := netIface.Addrs()
+		if err != nil {
+			return fmt.Errorf("failed to get addresses of %s: %w", iface.Name, err)
+		}
+		ipv4Addrs := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				ipv4Addrs = append(ipv4Addrs, ipNet.String())
+			}
+		}
+		iface.Addresses = append(ipv4Addrs, iface.Addresses...)
+	}
+	return nil
+}
+
+// SenseNetworkInterfaces returns the network interfaces, routes and forwarding state of the host
+func SenseNetworkInterfaces(ctx context.Context) (*NetworkInfo, error) {
+	ret, err := buildNetworkInfo(ctx, utils.HostPath(sysClassNetDir), hostNetNsProcPath())
+	if err != nil {
+		return ret, err
+	}
+
+	// sysctls and addresses are per network namespace, so they are read from the host namespace
+	err = utils.RunInNetNs(hostNetNsProcPath("ns", "net"), func() error {
+		readNetSysctls(ret, procSysNetDir)
+		return addIPv4Addresses(ret)
+	})
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseNetworkInterfaces failed to read host network namespace state", helpers.Error(err))
+	}
+
+	return ret, nil
+}
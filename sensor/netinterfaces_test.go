@@ -0,0 +1,83 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readSysClassNet(t *testing.T) {
+	ifaces, err := readSysClassNet("testdata/sys/class/net")
+	require.NoError(t, err)
+
+	assert.Equal(t, []NetworkInterface{
+		{Name: "lo", Index: 1, Kind: "loopback", OperState: "unknown", MTU: 65536, Up: true, Addresses: []string{}},
+		{Name: "eth0", Index: 2, Kind: "device", MACAddress: "52:54:00:12:34:56", OperState: "up", MTU: 1500, Up: true, Promiscuous: true, Addresses: []string{}},
+		{Name: "cni0", Index: 3, Kind: "bridge", MACAddress: "6a:1b:2c:3d:4e:5f", OperState: "up", MTU: 1450, Up: true, Addresses: []string{}},
+		{Name: "veth1a2b3c", Index: 4, Kind: "veth", MACAddress: "9e:8f:7a:6b:5c:4d", OperState: "up", MTU: 1450, Up: true, Master: "cni0", Addresses: []string{}},
+		{Name: "flannel.1", Index: 6, Kind: "vxlan", MACAddress: "1e:2d:3c:4b:5a:69", OperState: "unknown", MTU: 1450, Up: true, Addresses: []string{}},
+		{Name: "tun0", Index: 7, Kind: "tun", OperState: "unknown", MTU: 1500, Up: true, Addresses: []string{}},
+	}, ifaces)
+}
+
+func Test_parseProcNetRoute(t *testing.T) {
+	content, err := os.ReadFile("testdata/proc/1/net/route")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Route{
+		{Family: RouteFamilyIPv4, Interface: "eth0", Destination: "0.0.0.0/0", Gateway: "192.168.1.1", Metric: 100},
+		{Family: RouteFamilyIPv4, Interface: "eth0", Destination: "192.168.1.0/24", Metric: 100},
+		{Family: RouteFamilyIPv4, Interface: "cni0", Destination: "10.244.0.0/24"},
+		{Family: RouteFamilyIPv4, Interface: "flannel.1", Destination: "10.244.1.0/24", Gateway: "10.244.1.0"},
+	}, parseProcNetRoute(content))
+}
+
+func Test_parseProcNetIPv6Route(t *testing.T) {
+	content, err := os.ReadFile("testdata/proc/1/net/ipv6_route")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Route{
+		{Family: RouteFamilyIPv6, Interface: "eth0", Destination: "fd00::/64", Metric: 256},
+		{Family: RouteFamilyIPv6, Interface: "eth0", Destination: "fe80::/64", Metric: 256},
+		{Family: RouteFamilyIPv6, Interface: "eth0", Destination: "::/0", Gateway: "fd00::1", Metric: 1024},
+	}, parseProcNetIPv6Route(content))
+}
+
+func Test_buildNetworkInfo(t *testing.T) {
+	info, err := buildNetworkInfo(context.TODO(), "testdata/sys/class/net", "testdata/proc/1")
+	require.NoError(t, err)
+
+	assert.Len(t, info.Routes, 7)
+	assert.Equal(t, []Route{
+		{Family: RouteFamilyIPv4, Interface: "eth0", Destination: "0.0.0.0/0", Gateway: "192.168.1.1", Metric: 100},
+		{Family: RouteFamilyIPv6, Interface: "eth0", Destination: "::/0", Gateway: "fd00::1", Metric: 1024},
+	}, info.DefaultRoutes)
+	assert.Equal(t, []string{"eth0"}, info.PromiscuousInterfaces)
+	assert.Equal(t, []string{"::1/128"}, info.Interfaces[0].Addresses)
+	assert.Equal(t, []string{"fd00::10/64", "fe80::5054:ff:fe12:3456/64"}, info.Interfaces[1].Addresses)
+
+	_, err = buildNetworkInfo(context.TODO(), "testdata/sys/class/missing", "testdata/proc/1")
+	assert.Error(t, err)
+}
+
+func Test_readNetSysctls(t *testing.T) {
+	info, err := buildNetworkInfo(context.TODO(), "testdata/sys/class/net", "testdata/proc/1")
+	require.NoError(t, err)
+
+	readNetSysctls(info, "testdata/proc/sys/net")
+	assert.Equal(t, 1, *info.IPv4Forwarding)
+	assert.Equal(t, 0, *info.IPv6Forwarding)
+	assert.Equal(t, 2, *info.RPFilter)
+
+	eth0 := info.Interfaces[1]
+	assert.Equal(t, 1, *eth0.IPv4Forwarding)
+	assert.Equal(t, 0, *eth0.IPv6Forwarding)
+	assert.Equal(t, 1, *eth0.RPFilter)
+
+	cni0 := info.Interfaces[2]
+	assert.Equal(t, 0, *cni0.RPFilter)
+	assert.Nil(t, cni0.IPv6Forwarding)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
@@ -12,18 +15,54 @@ import (
 
 const (
 	tcpListeningState = 10
+
+	// hostNetNsPID is a process which is always running in the host network namespace.
+	// Reading `/proc/<pid>/net` of it shows the host sockets even if the scanner
+	// is not running with `hostNetwork`.
+	hostNetNsPID = 1
 )
 
 var (
-	ProcNetTCPPaths  = []string{"/proc/net/tcp", "/proc/net/tcp6"}
-	ProcNetUDPPaths  = []string{"/proc/net/udp", "/proc/net/udp6", "/proc/net/udplite", "/proc/net/udplite6"}
-	ProcNetICMPPaths = []string{"/proc/net/icmp", "/proc/net/icmp6"}
+	// paths relative to `/proc/<pid>`
+	ProcNetTCPPaths  = []string{"net/tcp", "net/tcp6"}
+	ProcNetUDPPaths  = []string{"net/udp", "net/udp6", "net/udplite", "net/udplite6"}
+	ProcNetICMPPaths = []string{"net/icmp", "net/icmp6"}
 )
 
 type OpenPortsStatus struct {
 	TcpPorts  []procspy.Connection `json:"tcpPorts"`
 	UdpPorts  []procspy.Connection `json:"udpPorts"`
 	ICMPPorts []procspy.Connection `json:"icmpPorts"`
+
+	// Listening sockets of every distinct network namespace on the node.
+	// Filled only by `SenseOpenPortsAllNetNs`.
+	NetNamespaces []NetNsOpenPorts `json:"netNamespaces,omitempty"`
+}
+
+// NetNsOpenPorts holds the listening sockets of a single network namespace
+type NetNsOpenPorts struct {
+	// The network namespace identifier.
+	// Example: net:[4026531840]
+	NetNs string `json:"netNs"`
+
+	// The processes running in the network namespace
+	PIDs []int32 `json:"pids"`
+
+	OpenPortsStatus `json:",inline"`
+}
+
+// hostNetNsProcPath returns a path under `/proc/<pid>` of a process running in the host network namespace
+func hostNetNsProcPath(elem ...string) string {
+	return path.Join(append([]string{procDirName, strconv.Itoa(hostNetNsPID)}, elem...)...)
+}
+
+// procNetPaths returns the full paths of `pathsList` for the network namespace of process `pid`
+func procNetPaths(procDir string, pid int32, pathsList []string) []string {
+	res := make([]string, 0, len(pathsList))
+	for i := range pathsList {
+		res = append(res, path.Join(procDir, strconv.Itoa(int(pid)), pathsList[i]))
+	}
+	return res
 }
 
 func getOpenedPorts(pathsList []string) ([]procspy.Connection, error) {
@@ -41,29 +80,112 @@ func getOpenedPorts(pathsList []string) ([]procspy.Connection, error) {
 	return res, nil
 }
 
-func SenseOpenPorts(ctx context.Context) (*OpenPortsStatus, error) {
-	// TODO: take process name. walks on ProcNetTCPPaths for each process in the system
+// senseNetNsOpenPorts returns the listening sockets in the network namespace of process `pid`
+func senseNetNsOpenPorts(ctx context.Context, procDir string, pid int32) OpenPortsStatus {
 	res := OpenPortsStatus{TcpPorts: make([]procspy.Connection, 0)}
 	// tcp
-	ports, err := getOpenedPorts(ProcNetTCPPaths)
+	paths := procNetPaths(procDir, pid, ProcNetTCPPaths)
+	ports, err := getOpenedPorts(paths)
 	if err != nil {
-		logger.L().Ctx(ctx).Warning("In SenseOpenPorts", helpers.String("paths", fmt.Sprintf("%v", ProcNetTCPPaths)), helpers.Error(err))
+		logger.L().Ctx(ctx).Warning("In SenseOpenPorts", helpers.String("paths", fmt.Sprintf("%v", paths)), helpers.Error(err))
 	} else {
 		res.TcpPorts = ports
 	}
 	// udp
-	ports, err = getOpenedPorts(ProcNetUDPPaths)
+	paths = procNetPaths(procDir, pid, ProcNetUDPPaths)
+	ports, err = getOpenedPorts(paths)
 	if err != nil {
-		logger.L().Ctx(ctx).Warning("In SenseOpenPorts", helpers.String("paths", fmt.Sprintf("%v", ProcNetUDPPaths)), helpers.Error(err))
+		logger.L().Ctx(ctx).Warning("In SenseOpenPorts", helpers.String("paths", fmt.Sprintf("%v", paths)), helpers.Error(err))
 	} else {
 		res.UdpPorts = ports
 	}
 	// icmp
-	ports, err = getOpenedPorts(ProcNetICMPPaths)
+	paths = procNetPaths(procDir, pid, ProcNetICMPPaths)
+	ports, err = getOpenedPorts(paths)
 	if err != nil {
-		logger.L().Ctx(ctx).Warning("In SenseOpenPorts", helpers.String("paths", fmt.Sprintf("%v", ProcNetICMPPaths)), helpers.Error(err))
+		logger.L().Ctx(ctx).Warning("In SenseOpenPorts", helpers.String("paths", fmt.Sprintf("%v", paths)), helpers.Error(err))
 	} else {
 		res.ICMPPorts = ports
 	}
+	return res
+}
+
+// listNetNamespaces walks on `/proc/*/ns/net` and returns the PIDs of each distinct network namespace,
+// except the host network namespace (of `hostNetNsPID`), which is reported on its own.
+// The PIDs of each namespace are sorted.
+func listNetNamespaces(procDir string) (map[string][]int32, error) {
+	res, err := listNamespaces(procDir, "net")
+	if err != nil {
+		return nil, err
+	}
+	if hostNs, err := os.Readlink(path.Join(procDir, strconv.Itoa(hostNetNsPID), "ns", "net")); err == nil {
+		delete(res, hostNs)
+	}
+	return res, nil
+}
+
+// listNamespaces returns the PIDs of every namespace of type `nsType` (e.g. net, mnt), by the namespace link,
+// e.g. net:[4026531840]
+func listNamespaces(procDir, nsType string) (map[string][]int32, error) {
+	pidDirs, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processes dir: %w", err)
+	}
+
+	res := map[string][]int32{}
+	for _, pidDir := range pidDirs {
+		// since processes are about to die in the middle of the loop, we will ignore next errors
+		pid, err := strconv.ParseInt(pidDir.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		ns, err := os.Readlink(path.Join(procDir, pidDir.Name(), "ns", nsType))
+		if err != nil {
+			continue
+		}
+		res[ns] = append(res[ns], int32(pid))
+	}
+
+	for ns := range res {
+		sort.Slice(res[ns], func(i, j int) bool { return res[ns][i] < res[ns][j] })
+	}
+
+	return res, nil
+}
+
+// SenseOpenPorts returns the listening sockets of the host network namespace
+func SenseOpenPorts(ctx context.Context) (*OpenPortsStatus, error) {
+	res := senseNetNsOpenPorts(ctx, procDirName, hostNetNsPID)
 	return &res, nil
 }
+
+// SenseOpenPortsAllNetNs returns the listening sockets of the host network namespace,
+// along with the listening sockets of every other distinct network namespace on the node
+func SenseOpenPortsAllNetNs(ctx context.Context) (*OpenPortsStatus, error) {
+	res, err := SenseOpenPorts(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	namespaces, err := listNetNamespaces(procDirName)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseOpenPortsAllNetNs failed to list network namespaces", helpers.Error(err))
+		return res, nil
+	}
+
+	res.NetNamespaces = make([]NetNsOpenPorts, 0, len(namespaces))
+	for netNs, pids := range namespaces {
+		res.NetNamespaces = append(res.NetNamespaces, NetNsOpenPorts{
+			NetNs:           netNs,
+			PIDs:            pids,
+			OpenPortsStatus: senseNetNsOpenPorts(ctx, procDirName, pids[0]),
+		})
+	}
+
+	// make the output deterministic
+	sort.Slice(res.NetNamespaces, func(i, j int) bool {
+		return res.NetNamespaces[i].PIDs[0] < res.NetNamespaces[j].PIDs[0]
+	})
+
+	return res, nil
+}
@@ -3,6 +3,9 @@ package sensor
 import (
 	"context"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSenseOpenPorts(t *testing.T) {
@@ -11,3 +14,43 @@ func TestSenseOpenPorts(t *testing.T) {
 		t.Errorf("%v", err)
 	}
 }
+
+func Test_listNetNamespaces(t *testing.T) {
+	namespaces, err := listNetNamespaces("testdata/proc")
+	require.NoError(t, err)
+	// the host network namespace of PID 1 (and 42) is reported on its own
+	assert.Equal(t, map[string][]int32{
+		"net:[4026532201]": {100},
+	}, namespaces)
+}
+
+func Test_senseNetNsOpenPorts(t *testing.T) {
+	tests := []struct {
+		name      string
+		pid       int32
+		wantPorts []uint16
+	}{
+		{
+			name:      "host namespace",
+			pid:       1,
+			wantPorts: []uint16{7888},
+		},
+		{
+			name:      "pod namespace",
+			pid:       100,
+			wantPorts: []uint16{8080},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := senseNetNsOpenPorts(context.TODO(), "testdata/proc", tt.pid)
+			ports := []uint16{}
+			for _, c := range res.TcpPorts {
+				ports = append(ports, c.LocalPort)
+			}
+			assert.Equal(t, tt.wantPorts, ports)
+			assert.Empty(t, res.UdpPorts)
+			assert.Empty(t, res.ICMPPorts)
+		})
+	}
+}
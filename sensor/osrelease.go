@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/kubescape/go-logger"
@@ -13,22 +14,81 @@ import (
 )
 
 const (
-	etcDirName               = "/etc"
-	osReleaseFileSuffix      = "os-release"
-	appArmorProfilesFileName = "/sys/kernel/security/apparmor/profiles"
-	seLinuxConfigFileName    = "/etc/selinux/semanage.conf"
+	etcDirName          = "/etc"
+	osReleaseFileSuffix = "os-release"
+
+	// The fallback of /etc/os-release, see os-release(5)
+	usrLibOsReleaseFileName = "/usr/lib/os-release"
 )
 
 func SenseOsRelease() ([]byte, error) {
-	osFileName, err := getOsReleaseFile()
-	if err == nil {
-		return utils.ReadFileOnHostFileSystem(path.Join(etcDirName, osFileName))
+	return readOsReleaseFile(utils.HostFileSystemDefaultLocation)
+}
+
+// readOsReleaseFile reads the os-release file of the host file system at `rootDir`
+func readOsReleaseFile(rootDir string) ([]byte, error) {
+	osFileName, err := getOsReleaseFile(rootDir)
+	if err != nil {
+		return []byte{}, fmt.Errorf("failed to find os-release file: %v", err)
 	}
-	return []byte{}, fmt.Errorf("failed to find os-release file: %v", err)
+	return readHostConfFile(rootDir, osFileName)
+}
+
+// OSDistro holds the distribution identification of os-release
+type OSDistro struct {
+	// Example: debian, ubuntu, rhel, alpine
+	ID string `json:"id"`
+
+	// Example: 12, 22.04, 9.3, 3.19.1
+	VersionID string `json:"versionID,omitempty"`
+
+	// Example: Debian GNU/Linux 12 (bookworm)
+	PrettyName string `json:"prettyName,omitempty"`
 }
 
-func getOsReleaseFile() (string, error) {
-	hostEtcDir := utils.HostPath(etcDirName)
+// parseOsRelease parses the KEY=value lines of an os-release file, unquoting the values
+func parseOsRelease(content []byte) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// readOsDistro reads the distribution of the host file system at `rootDir` from its os-release file
+func readOsDistro(rootDir string) (*OSDistro, error) {
+	content, err := readOsReleaseFile(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	values := parseOsRelease(content)
+	return &OSDistro{ID: values["ID"], VersionID: values["VERSION_ID"], PrettyName: values["PRETTY_NAME"]}, nil
+}
+
+// getOsReleaseFile returns the path of the os-release file of the host file system at `rootDir`:
+// /etc/os-release, /usr/lib/os-release, or else a legacy file of /etc with the os-release suffix.
+// The legacy files, such as /etc/centos-release, are not always in the KEY=value format, so they come last.
+func getOsReleaseFile(rootDir string) (string, error) {
+	for _, fileName := range []string{path.Join(etcDirName, osReleaseFileSuffix), usrLibOsReleaseFileName} {
+		if _, err := os.Stat(hostFilePath(rootDir, fileName)); err == nil {
+			return fileName, nil
+		}
+	}
+
+	hostEtcDir := path.Join(rootDir, etcDirName)
 	etcDir, err := os.Open(hostEtcDir)
 	if err != nil {
 		return "", fmt.Errorf("failed to open etc dir: %v", err)
@@ -39,7 +99,7 @@ func getOsReleaseFile() (string, error) {
 		for idx := range etcSons {
 			if strings.HasSuffix(etcSons[idx], osReleaseFileSuffix) {
 				logger.L().Debug("os release file found", helpers.String("filename", etcSons[idx]))
-				return etcSons[idx], nil
+				return path.Join(etcDirName, etcSons[idx]), nil
 			}
 		}
 	}
@@ -50,40 +110,12 @@ func SenseKernelVersion() ([]byte, error) {
 	return utils.ReadFileOnHostFileSystem(path.Join(procDirName, "version"))
 }
 
-func getAppArmorStatus() string {
-	statusStr := "unloaded"
-	hostAppArmorProfilesFileName := utils.HostPath(appArmorProfilesFileName)
-	profFile, err := os.Open(hostAppArmorProfilesFileName)
-	if err == nil {
-		defer profFile.Close()
-		statusStr = "stopped"
-		content, err := utils.ReadFileOnHostFileSystem(appArmorProfilesFileName)
-		if err == nil && len(content) > 0 {
-			statusStr = string(content)
-		}
-	}
-	return statusStr
-}
-
-func getSELinuxStatus() string {
-	statusStr := "not found"
-	hostAppArmorProfilesFileName := utils.HostPath(seLinuxConfigFileName)
-	conFile, err := os.Open(hostAppArmorProfilesFileName)
-	if err == nil {
-		defer conFile.Close()
-		content, err := utils.ReadFileOnHostFileSystem(appArmorProfilesFileName)
-		if err == nil && len(content) > 0 {
-			statusStr = string(content)
-		}
-	}
-	return statusStr
-}
-
 func SenseLinuxSecurityHardening() (*ds.LinuxSecurityHardeningStatus, error) {
 	res := ds.LinuxSecurityHardeningStatus{}
 
-	res.AppArmor = getAppArmorStatus()
-	res.SeLinux = getSELinuxStatus()
+	res.AppArmor, res.AppArmorStatus = getAppArmorStatus()
+	res.SeLinux, res.SeLinuxStatus = getSELinuxStatus()
+	senseKernelHardening(utils.HostFileSystemDefaultLocation, procDirName, &res)
 
 	return &res, nil
 }
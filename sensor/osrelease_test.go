@@ -0,0 +1,58 @@
+package sensor
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getOsReleaseFile(t *testing.T) {
+	fileName, err := getOsReleaseFile("testdata/packages/debian")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/os-release", fileName)
+
+	// /etc/os-release is a link to /usr/lib/os-release
+	distro, err := readOsDistro("testdata/packages/rhel")
+	require.NoError(t, err)
+	assert.Equal(t, "rhel", distro.ID)
+
+	// no /etc/os-release, the legacy /etc/centos-release is not in the KEY=value format
+	rootDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "etc"), 0o755))
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "usr/lib"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "etc/centos-release"), []byte("CentOS Linux release 7.9.2009 (Core)\n"), 0o644))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "usr/lib/os-release"), []byte("ID=centos\nVERSION_ID=7\n"), 0o644))
+	fileName, err = getOsReleaseFile(rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/lib/os-release", fileName)
+	distro, err = readOsDistro(rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, &OSDistro{ID: "centos", VersionID: "7"}, distro)
+
+	// /etc/os-release is preferred over the legacy files
+	require.NoError(t, os.Symlink("/usr/lib/os-release", path.Join(rootDir, "etc/os-release")))
+	fileName, err = getOsReleaseFile(rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/os-release", fileName)
+
+	// only a legacy file
+	require.NoError(t, os.Remove(path.Join(rootDir, "etc/os-release")))
+	require.NoError(t, os.Remove(path.Join(rootDir, "usr/lib/os-release")))
+	fileName, err = getOsReleaseFile(rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/centos-release", fileName)
+
+	// no /etc dir
+	rootDir = t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "usr/lib"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "usr/lib/os-release"), []byte("ID=fedora\n"), 0o644))
+	fileName, err = getOsReleaseFile(rootDir)
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/lib/os-release", fileName)
+
+	_, err = getOsReleaseFile("testdata/packages/missing")
+	assert.Error(t, err)
+}
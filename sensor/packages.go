@@ -0,0 +1,351 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/rpmdb"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	dpkgStatusFileName   = "/var/lib/dpkg/status"
+	apkInstalledFileName = "/lib/apk/db/installed"
+
+	PackageTypeDeb = "deb"
+	PackageTypeRPM = "rpm"
+	PackageTypeAPK = "apk"
+)
+
+// The rpm database locations, by order of precedence. /var/lib/rpm is a link to /usr/lib/sysimage/rpm on newer distributions.
+var rpmDatabaseFileNames = []string{
+	"/usr/lib/sysimage/rpm/rpmdb.sqlite",
+	"/var/lib/rpm/rpmdb.sqlite",
+	"/usr/lib/sysimage/rpm/Packages",
+	"/var/lib/rpm/Packages",
+}
+
+// OSPackage holds an installed OS package
+type OSPackage struct {
+	Name string `json:"name"`
+
+	// The full version, including the epoch and the release
+	// Example: 1:3.0.7-27.el9
+	Version string `json:"version"`
+
+	// Example: amd64, x86_64, noarch
+	Arch string `json:"arch,omitempty"`
+
+	// The source package (if it has another name)
+	// Example: openssl (of libssl3)
+	Source string `json:"source,omitempty"`
+
+	License string `json:"license,omitempty"`
+
+	// The maintainer or the vendor of the package
+	Supplier string `json:"supplier,omitempty"`
+
+	// deb, rpm or apk
+	Type string `json:"type"`
+
+	// The package URL, see https://github.com/package-url/purl-spec
+	// Example: pkg:deb/debian/bash@5.2.15-2%2Bb2?arch=amd64&distro=debian-12
+	PURL string `json:"purl"`
+
+	// rpm only: the epoch, and the version and release which make up `Version`
+	epoch      *int
+	rpmVersion string
+}
+
+// PackagesInfo holds the installed packages of the host
+type PackagesInfo struct {
+	Hostname string    `json:"hostname,omitempty"`
+	Distro   *OSDistro `json:"distro,omitempty"`
+
+	// The package databases which were read
+	// Example: ["/var/lib/dpkg/status"]
+	Databases []string `json:"databases"`
+
+	Packages []OSPackage `json:"packages"`
+}
+
+// parseDpkgStatus parses the installed packages of a dpkg status file
+func parseDpkgStatus(content []byte) []OSPackage {
+	var packages []OSPackage
+	fields := map[string]string{}
+	lastField := ""
+
+	addPackage := func() {
+		defer func() { fields = map[string]string{} }()
+		// Example: install ok installed
+		status := strings.Fields(fields["Status"])
+		if fields["Package"] == "" || len(status) != 3 || status[2] != "installed" {
+			return
+		}
+		pkg := OSPackage{
+			Name:     fields["Package"],
+			Version:  fields["Version"],
+			Arch:     fields["Architecture"],
+			Supplier: fields["Maintainer"],
+			Type:     PackageTypeDeb,
+		}
+		// Example: openssl (3.0.11-1~deb12u2)
+		if source, _, _ := strings.Cut(fields["Source"], " "); source != pkg.Name {
+			pkg.Source = source
+		}
+		packages = append(packages, pkg)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			addPackage()
+		case line[0] == ' ' || line[0] == '\t':
+			// a continuation of a multiline field, such as Description or Conffiles
+			if lastField != "" {
+				fields[lastField] += "\n" + strings.TrimSpace(line)
+			}
+		default:
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			lastField = key
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	addPackage()
+	return packages
+}
+
+// parseAPKInstalled parses the packages of an apk installed database
+func parseAPKInstalled(content []byte) []OSPackage {
+	var packages []OSPackage
+	var pkg *OSPackage
+
+	addPackage := func() {
+		if pkg != nil && pkg.Name != "" {
+			if pkg.Source == pkg.Name {
+				pkg.Source = ""
+			}
+			packages = append(packages, *pkg)
+		}
+		pkg = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			addPackage()
+			continue
+		}
+		// single letter fields, e.g. `P:busybox`
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		if pkg == nil {
+			pkg = &OSPackage{Type: PackageTypeAPK}
+		}
+		value := line[2:]
+		switch line[0] {
+		case 'P':
+			pkg.Name = value
+		case 'V':
+			pkg.Version = value
+		case 'A':
+			pkg.Arch = value
+		case 'L':
+			pkg.License = value
+		case 'm':
+			pkg.Supplier = value
+		case 'o':
+			pkg.Source = value
+		}
+	}
+	addPackage()
+	return packages
+}
+
+// rpmPackages converts the packages of an rpm database
+func rpmPackages(rpmPackages []rpmdb.Package) []OSPackage {
+	packages := make([]OSPackage, 0, len(rpmPackages))
+	for _, rpmPackage := range rpmPackages {
+		pkg := OSPackage{
+			Name:       rpmPackage.Name,
+			Arch:       rpmPackage.Arch,
+			License:    rpmPackage.License,
+			Supplier:   rpmPackage.Vendor,
+			Type:       PackageTypeRPM,
+			epoch:      rpmPackage.Epoch,
+			rpmVersion: rpmPackage.Version + "-" + rpmPackage.Release,
+		}
+		pkg.Version = pkg.rpmVersion
+		if pkg.epoch != nil {
+			pkg.Version = fmt.Sprintf("%d:%s", *pkg.epoch, pkg.rpmVersion)
+		}
+		// Example: bash-5.1.8-6.el9.src.rpm
+		source := strings.TrimSuffix(rpmPackage.SourceRPM, ".src.rpm")
+		for i := 0; i < 2; i++ {
+			if idx := strings.LastIndex(source, "-"); idx > 0 {
+				source = source[:idx]
+			}
+		}
+		if source != pkg.Name {
+			pkg.Source = source
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// packageURL builds the purl of `pkg`, qualified with the distro
+func packageURL(pkg *OSPackage, distro *OSDistro) string {
+	namespace := ""
+	qualifiers := url.Values{}
+	if distro != nil && distro.ID != "" {
+		namespace = purlEscape(distro.ID) + "/"
+		distroQualifier := distro.ID
+		if distro.VersionID != "" {
+			distroQualifier += "-" + distro.VersionID
+		}
+		qualifiers.Set("distro", distroQualifier)
+	}
+	if pkg.Arch != "" {
+		qualifiers.Set("arch", pkg.Arch)
+	}
+
+	version := pkg.Version
+	if pkg.Type == PackageTypeRPM {
+		// the rpm epoch is a qualifier
+		version = pkg.rpmVersion
+		if pkg.epoch != nil {
+			qualifiers.Set("epoch", strconv.Itoa(*pkg.epoch))
+		}
+	}
+	if pkg.Source != "" && pkg.Type != PackageTypeAPK {
+		qualifiers.Set("upstream", pkg.Source)
+	}
+
+	purl := fmt.Sprintf("pkg:%s/%s%s@%s", pkg.Type, namespace, purlEscape(pkg.Name), purlEscape(version))
+	// Encode sorts the qualifiers by key, as required by the purl spec
+	if len(qualifiers) > 0 {
+		purl += "?" + qualifiers.Encode()
+	}
+	return purl
+}
+
+// purlEscape percent-encodes a purl name or version, including `+` (e.g. of libstdc++6)
+func purlEscape(value string) string {
+	return strings.ReplaceAll(url.PathEscape(value), "+", "%2B")
+}
+
+// existingHostFile returns the host path of the first of `fileNames` which exists under `rootDir`
+func existingHostFile(rootDir string, fileNames ...string) (string, string) {
+	for _, fileName := range fileNames {
+		hostPath := path.Join(rootDir, fileName)
+		if _, err := os.Stat(hostPath); err == nil {
+			return fileName, hostPath
+		}
+	}
+	return "", ""
+}
+
+func sensePackages(ctx context.Context, rootDir string) (*PackagesInfo, error) {
+	info := PackagesInfo{Databases: []string{}, Packages: []OSPackage{}}
+
+	distro, err := readOsDistro(rootDir)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to read os-release", helpers.Error(err))
+	} else {
+		info.Distro = distro
+	}
+	if hostname, err := os.ReadFile(path.Join(rootDir, "/etc/hostname")); err == nil {
+		info.Hostname = strings.TrimSpace(string(hostname))
+	}
+
+	readers := []struct {
+		fileNames []string
+		read      func(hostPath string) ([]OSPackage, error)
+	}{
+		{[]string{dpkgStatusFileName}, func(hostPath string) ([]OSPackage, error) {
+			content, err := os.ReadFile(hostPath)
+			return parseDpkgStatus(content), err
+		}},
+		{rpmDatabaseFileNames, func(hostPath string) ([]OSPackage, error) {
+			packages, err := rpmdb.ReadPackages(hostPath)
+			return rpmPackages(packages), err
+		}},
+		{[]string{apkInstalledFileName}, func(hostPath string) ([]OSPackage, error) {
+			content, err := os.ReadFile(hostPath)
+			return parseAPKInstalled(content), err
+		}},
+	}
+
+	var readErr error
+	for _, reader := range readers {
+		fileName, hostPath := existingHostFile(rootDir, reader.fileNames...)
+		if fileName == "" {
+			continue
+		}
+		packages, err := reader.read(hostPath)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to read package database", helpers.String("path", fileName), helpers.Error(err))
+			readErr = err
+			continue
+		}
+		info.Databases = append(info.Databases, fileName)
+		info.Packages = append(info.Packages, packages...)
+	}
+	if len(info.Databases) == 0 && readErr != nil {
+		return nil, &SenseError{
+			Massage:  "failed to read the package databases",
+			Function: "SensePackages",
+			Code:     http.StatusInternalServerError,
+			err:      readErr,
+		}
+	}
+
+	for i := range info.Packages {
+		info.Packages[i].PURL = packageURL(&info.Packages[i], info.Distro)
+	}
+	sort.SliceStable(info.Packages, func(i, j int) bool {
+		if info.Packages[i].Name != info.Packages[j].Name {
+			return info.Packages[i].Name < info.Packages[j].Name
+		}
+		return info.Packages[i].Arch < info.Packages[j].Arch
+	})
+	return &info, nil
+}
+
+// SensePackages returns the installed OS packages of the host, read from the dpkg, rpm and apk databases.
+// `format` is empty for the package list, or one of the SBOM formats (see `ParseSBOMFormat`).
+func SensePackages(ctx context.Context, format string) (interface{}, error) {
+	info, err := sensePackages(ctx, utils.HostFileSystemDefaultLocation)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case SBOMFormatCycloneDX:
+		return newCycloneDXBOM(info, time.Now()), nil
+	case SBOMFormatSPDX:
+		return newSPDXDocument(info, time.Now()), nil
+	}
+	return info, nil
+}
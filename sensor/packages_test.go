@@ -0,0 +1,180 @@
+package sensor
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sensePackagesDpkg(t *testing.T) {
+	info, err := sensePackages(context.TODO(), "testdata/packages/debian")
+	require.NoError(t, err)
+
+	assert.Equal(t, "node-a", info.Hostname)
+	assert.Equal(t, &OSDistro{ID: "debian", VersionID: "12", PrettyName: "Debian GNU/Linux 12 (bookworm)"}, info.Distro)
+	assert.Equal(t, []string{"/var/lib/dpkg/status"}, info.Databases)
+
+	// telnet is removed, only its config files are left
+	assert.Equal(t, []OSPackage{
+		{
+			Name:     "bash",
+			Version:  "5.2.15-2+b2",
+			Arch:     "amd64",
+			Supplier: "Matthias Klose <doko@debian.org>",
+			Type:     PackageTypeDeb,
+			PURL:     "pkg:deb/debian/bash@5.2.15-2%2Bb2?arch=amd64&distro=debian-12",
+		},
+		{
+			Name:     "libssl3",
+			Version:  "3.0.11-1~deb12u2",
+			Arch:     "amd64",
+			Source:   "openssl",
+			Supplier: "Debian OpenSSL Team <pkg-openssl-devel@alioth-lists.debian.net>",
+			Type:     PackageTypeDeb,
+			PURL:     "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl",
+		},
+		{
+			Name:     "libstdc++6",
+			Version:  "12.2.0-14",
+			Arch:     "amd64",
+			Source:   "gcc-12",
+			Supplier: "Debian GCC Maintainers <debian-gcc@lists.debian.org>",
+			Type:     PackageTypeDeb,
+			PURL:     "pkg:deb/debian/libstdc%2B%2B6@12.2.0-14?arch=amd64&distro=debian-12&upstream=gcc-12",
+		},
+	}, info.Packages)
+}
+
+func Test_sensePackagesAPK(t *testing.T) {
+	info, err := sensePackages(context.TODO(), "testdata/packages/alpine")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/lib/apk/db/installed"}, info.Databases)
+	require.Len(t, info.Packages, 2)
+	assert.Equal(t, OSPackage{
+		Name:     "busybox-binsh",
+		Version:  "1.36.1-r15",
+		Arch:     "x86_64",
+		Source:   "busybox",
+		License:  "GPL-2.0-only",
+		Supplier: "Sören Tempel <soeren+alpine@soeren-tempel.net>",
+		Type:     PackageTypeAPK,
+		PURL:     "pkg:apk/alpine/busybox-binsh@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1",
+	}, info.Packages[0])
+	// the origin is the package itself
+	assert.Equal(t, "musl", info.Packages[1].Name)
+	assert.Empty(t, info.Packages[1].Source)
+}
+
+func Test_sensePackagesRPM(t *testing.T) {
+	info, err := sensePackages(context.TODO(), "testdata/packages/rhel")
+	require.NoError(t, err)
+
+	// os-release is a relative link to /usr/lib/os-release
+	assert.Equal(t, "rhel", info.Distro.ID)
+	assert.Equal(t, []string{"/var/lib/rpm/Packages"}, info.Databases)
+	require.Len(t, info.Packages, 2)
+	assert.Equal(t, "bash", info.Packages[0].Name)
+	assert.Equal(t, "5.1.8-6.el9", info.Packages[0].Version)
+	assert.Equal(t, "pkg:rpm/rhel/bash@5.1.8-6.el9?arch=x86_64&distro=rhel-9.3", info.Packages[0].PURL)
+	assert.Equal(t, "Red Hat, Inc.", info.Packages[0].Supplier)
+	assert.Equal(t, "zlib", info.Packages[1].Name)
+}
+
+func Test_sensePackagesErrors(t *testing.T) {
+	// no package database
+	info, err := sensePackages(context.TODO(), "testdata/packages/missing")
+	require.NoError(t, err)
+	assert.Nil(t, info.Distro)
+	assert.Empty(t, info.Packages)
+
+	_, err = sensePackages(context.TODO(), "testdata/packages/broken")
+	assert.ErrorContains(t, err, "failed to read the package databases")
+}
+
+func Test_packageURLRPMEpoch(t *testing.T) {
+	epoch := 1
+	pkg := OSPackage{Name: "openssl-libs", Version: "1:3.0.7-27.el9", Arch: "x86_64", Source: "openssl", Type: PackageTypeRPM,
+		epoch: &epoch, rpmVersion: "3.0.7-27.el9"}
+	assert.Equal(t, "pkg:rpm/rhel/openssl-libs@3.0.7-27.el9?arch=x86_64&distro=rhel-9.3&epoch=1&upstream=openssl",
+		packageURL(&pkg, &OSDistro{ID: "rhel", VersionID: "9.3"}))
+	assert.Equal(t, "pkg:rpm/openssl-libs@3.0.7-27.el9?arch=x86_64&epoch=1&upstream=openssl", packageURL(&pkg, nil))
+}
+
+func Test_ParseSBOMFormat(t *testing.T) {
+	format, err := ParseSBOMFormat(url.Values{})
+	require.NoError(t, err)
+	assert.Empty(t, format)
+
+	format, err = ParseSBOMFormat(url.Values{"format": {"spdx"}})
+	require.NoError(t, err)
+	assert.Equal(t, SBOMFormatSPDX, format)
+
+	_, err = ParseSBOMFormat(url.Values{"format": {"xml"}})
+	var senseErr *SenseError
+	require.ErrorAs(t, err, &senseErr)
+	assert.Equal(t, 400, senseErr.Code)
+}
+
+func Test_newCycloneDXBOM(t *testing.T) {
+	info, err := sensePackages(context.TODO(), "testdata/packages/debian")
+	require.NoError(t, err)
+	bom := newCycloneDXBOM(info, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Regexp(t, "^urn:uuid:[0-9a-f-]{36}$", bom.SerialNumber)
+	assert.Equal(t, "2024-01-02T03:04:05Z", bom.Metadata.Timestamp)
+	assert.Equal(t, "operating-system", bom.Metadata.Component.Type)
+	assert.Equal(t, "12", bom.Metadata.Component.Version)
+	require.Len(t, bom.Components, 3)
+	assert.Equal(t, CycloneDXComponent{
+		BOMRef:    "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl",
+		Type:      "library",
+		Name:      "libssl3",
+		Version:   "3.0.11-1~deb12u2",
+		Publisher: "Debian OpenSSL Team <pkg-openssl-devel@alioth-lists.debian.net>",
+		PURL:      "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64&distro=debian-12&upstream=openssl",
+		Properties: []CycloneDXProperty{
+			{Name: "kubescape:package:type", Value: "deb"},
+			{Name: "kubescape:package:source", Value: "openssl"},
+		},
+	}, bom.Components[1])
+}
+
+func Test_newSPDXDocument(t *testing.T) {
+	info, err := sensePackages(context.TODO(), "testdata/packages/alpine")
+	require.NoError(t, err)
+	doc := newSPDXDocument(info, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "host-scanner", doc.Name)
+	assert.Regexp(t, "^https://kubescape.io/spdxdocs/host-scanner/host-scanner-[0-9a-f-]{36}$", doc.DocumentNamespace)
+
+	// the operating system and its packages
+	require.Len(t, doc.Packages, 3)
+	assert.Equal(t, "OPERATING-SYSTEM", doc.Packages[0].PrimaryPackagePurpose)
+	assert.Equal(t, SPDXPackage{
+		Name:                  "busybox-binsh",
+		SPDXID:                "SPDXRef-Package-apk-busybox-binsh-0",
+		VersionInfo:           "1.36.1-r15",
+		Supplier:              "Organization: Sören Tempel <soeren+alpine@soeren-tempel.net>",
+		DownloadLocation:      "NOASSERTION",
+		LicenseConcluded:      "NOASSERTION",
+		LicenseDeclared:       "NOASSERTION",
+		LicenseComments:       "GPL-2.0-only",
+		SourceInfo:            "built from the apk source package busybox",
+		PrimaryPackagePurpose: "LIBRARY",
+		ExternalRefs: []SPDXExternalRef{
+			{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:apk/alpine/busybox-binsh@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1"},
+		},
+	}, doc.Packages[1])
+	assert.Equal(t, []SPDXRelationship{
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-OperatingSystem"},
+		{SPDXElementID: "SPDXRef-OperatingSystem", RelationshipType: "CONTAINS", RelatedSPDXElement: "SPDXRef-Package-apk-busybox-binsh-0"},
+		{SPDXElementID: "SPDXRef-OperatingSystem", RelationshipType: "CONTAINS", RelatedSPDXElement: "SPDXRef-Package-apk-musl-1"},
+	}, doc.Relationships)
+}
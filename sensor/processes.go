@@ -0,0 +1,117 @@
+package sensor
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+// nodeCriticalProcesses are the kubelet and the container runtime processes, by executable suffix
+var nodeCriticalProcesses = []struct {
+	name   string
+	suffix string
+}{
+	{name: "kubelet", suffix: "/kubelet"},
+	{name: "containerd", suffix: "/containerd"},
+	{name: "crio", suffix: "/crio"},
+	{name: "dockerd", suffix: "/dockerd"},
+}
+
+// runningProcess is a running process, found by `findNodeCriticalProcesses`
+type runningProcess struct {
+	name string
+	pid  int32
+}
+
+// processCmdLine returns the details of process `pid` from its `/proc/<pid>/cmdline`
+func processCmdLine(procDir string, pid uint) (*utils.ProcessDetails, error) {
+	cmdLine, err := os.ReadFile(path.Join(procDir, strconv.Itoa(int(pid)), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	res := &utils.ProcessDetails{PID: int32(pid), CmdLine: []string{}}
+	for _, arg := range bytes.Split(bytes.TrimRight(cmdLine, "\x00"), []byte{0}) {
+		res.CmdLine = append(res.CmdLine, string(arg))
+	}
+	return res, nil
+}
+
+// processComm returns the name of process `pid` from its `/proc/<pid>/comm`, which is set by the kernel
+// from the executable (also for kernel threads), unlike the cmdline which the process can rewrite
+func processComm(procDir string, pid int32) (string, error) {
+	comm, err := os.ReadFile(path.Join(procDir, strconv.Itoa(int(pid)), "comm"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(comm)), nil
+}
+
+// locateProcessesBySuffix walks on `/proc/*/cmdline` once, and returns for each of `suffixes`
+// the PID of the first process with an executable name which ends with it.
+// Like `utils.LocateProcessByExecSuffix`, executables without a path are matched as if they start with `/`.
+func locateProcessesBySuffix(procDir string, suffixes []string) map[string]int32 {
+	res := map[string]int32{}
+	pidDirs, err := os.ReadDir(procDir)
+	if err != nil {
+		return res
+	}
+	for _, pidDir := range pidDirs {
+		// since processes are about to die in the middle of the loop, we will ignore next errors
+		pid, err := strconv.ParseInt(pidDir.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		cmdLine, err := os.ReadFile(path.Join(procDir, pidDir.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		exe := bytes.SplitN(cmdLine, []byte{0}, 2)[0]
+		if len(exe) == 0 {
+			continue
+		}
+		if exe[0] != '/' {
+			exe = append([]byte{'/'}, exe...)
+		}
+		for _, suffix := range suffixes {
+			if _, found := res[suffix]; !found && bytes.HasSuffix(exe, []byte(suffix)) {
+				res[suffix] = int32(pid)
+			}
+		}
+	}
+	return res
+}
+
+// processSecurityLabel returns the label of process `pid` of the LSM `lsm` (e.g. selinux or apparmor).
+// The per LSM attribute of stacking kernels is preferred over the shared `attr/current`.
+func processSecurityLabel(procDir string, pid int32, lsm string) (string, error) {
+	pidDir := path.Join(procDir, strconv.Itoa(int(pid)))
+	content, err := os.ReadFile(path.Join(pidDir, "attr", lsm, "current"))
+	if err != nil {
+		content, err = os.ReadFile(path.Join(pidDir, "attr", "current"))
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(content), "\x00\n"), nil
+}
+
+// findNodeCriticalProcesses returns the running kubelet and container runtime processes under `procDir`
+func findNodeCriticalProcesses(procDir string) []runningProcess {
+	suffixes := make([]string, 0, len(nodeCriticalProcesses))
+	for _, proc := range nodeCriticalProcesses {
+		suffixes = append(suffixes, proc.suffix)
+	}
+	pids := locateProcessesBySuffix(procDir, suffixes)
+
+	res := make([]runningProcess, 0, len(pids))
+	for _, proc := range nodeCriticalProcesses {
+		if pid, ok := pids[proc.suffix]; ok {
+			res = append(res, runningProcess{name: proc.name, pid: pid})
+		}
+	}
+	return res
+}
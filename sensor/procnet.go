@@ -0,0 +1,117 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// procNetSocket is a socket of /proc/net/{tcp,udp}[6].
+// Unlike `procspy.Connection`, it keeps the state and the inode, which are needed to find the owning process.
+type procNetSocket struct {
+	Transport     string
+	LocalAddress  net.IP
+	LocalPort     uint16
+	RemoteAddress net.IP
+	RemotePort    uint16
+
+	// The kernel socket state (e.g. 0x0A for TCP_LISTEN)
+	State uint8
+	Inode uint64
+}
+
+// parseProcNetAddress parses an `address:port` of /proc/net/{tcp,udp}[6].
+// Addresses are printed as 32 bit words in host byte order.
+func parseProcNetAddress(s string) (net.IP, uint16, error) {
+	addr, port, found := strings.Cut(s, ":")
+	if !found {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	raw, err := hex.DecodeString(addr)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(raw[i:]))
+	}
+	portNum, err := strconv.ParseUint(port, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port %q", s)
+	}
+	return ip, uint16(portNum), nil
+}
+
+// parseProcNetSockets parses the content of /proc/net/{tcp,udp}[6] and returns all its sockets
+func parseProcNetSockets(content []byte, transport string) []procNetSocket {
+	res := make([]procNetSocket, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		localAddr, localPort, err := parseProcNetAddress(fields[1])
+		if err != nil {
+			continue
+		}
+		remoteAddr, remotePort, err := parseProcNetAddress(fields[2])
+		if err != nil {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			continue
+		}
+		res = append(res, procNetSocket{
+			Transport:     transport,
+			LocalAddress:  localAddr,
+			LocalPort:     localPort,
+			RemoteAddress: remoteAddr,
+			RemotePort:    remotePort,
+			State:         uint8(state),
+			Inode:         inode,
+		})
+	}
+	return res
+}
+
+// readProcNetSockets reads the sockets of `pathsList`, e.g. /proc/1/net/tcp and /proc/1/net/tcp6.
+// Files which can't be read are skipped, e.g. /proc/1/net/tcp6 when IPv6 is disabled.
+// Returns an error only if none of the files could be read.
+func readProcNetSockets(ctx context.Context, pathsList []string, transport string) ([]procNetSocket, error) {
+	res := make([]procNetSocket, 0)
+	var readErr error
+	read := 0
+	for _, p := range pathsList {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			logger.L().Ctx(ctx).Debug("In readProcNetSockets failed to read file", helpers.String("path", p), helpers.Error(err))
+			readErr = fmt.Errorf("failed to ReadFile(%s): %w", p, err)
+			continue
+		}
+		read++
+		res = append(res, parseProcNetSockets(content, transport)...)
+	}
+	if read == 0 && readErr != nil {
+		return res, readErr
+	}
+	return res, nil
+}
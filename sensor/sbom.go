@@ -0,0 +1,254 @@
+package sensor
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SBOMFormatCycloneDX = "cyclonedx"
+	SBOMFormatSPDX      = "spdx"
+
+	sbomToolName    = "host-scanner"
+	sbomToolVendor  = "kubescape"
+	spdxNoAssertion = "NOASSERTION"
+)
+
+// SPDX identifiers may only contain letters, numbers, `.` and `-`
+var spdxIDInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// ParseSBOMFormat parses the `format` query parameter: empty (the package list), `cyclonedx` or `spdx`
+func ParseSBOMFormat(values url.Values) (string, error) {
+	switch format := values.Get("format"); format {
+	case "", "json":
+		return "", nil
+	case SBOMFormatCycloneDX, SBOMFormatSPDX:
+		return format, nil
+	default:
+		return "", &SenseError{
+			Massage:  fmt.Sprintf("unknown format %q, expected %q or %q", format, SBOMFormatCycloneDX, SBOMFormatSPDX),
+			Function: "ParseSBOMFormat",
+			Code:     http.StatusBadRequest,
+		}
+	}
+}
+
+// CycloneDXBOM is a CycloneDX 1.5 JSON document, see https://cyclonedx.org/docs/1.5/json
+type CycloneDXBOM struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     CycloneDXMetadata    `json:"metadata"`
+	Components   []CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string         `json:"timestamp"`
+	Tools     CycloneDXTools `json:"tools"`
+
+	// The operating system of the host
+	Component *CycloneDXComponent `json:"component,omitempty"`
+}
+
+type CycloneDXTools struct {
+	Components []CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXComponent struct {
+	BOMRef      string              `json:"bom-ref,omitempty"`
+	Type        string              `json:"type"`
+	Group       string              `json:"group,omitempty"`
+	Name        string              `json:"name"`
+	Version     string              `json:"version,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Publisher   string              `json:"publisher,omitempty"`
+	Licenses    []CycloneDXLicense  `json:"licenses,omitempty"`
+	PURL        string              `json:"purl,omitempty"`
+	Properties  []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+type CycloneDXLicense struct {
+	License CycloneDXLicenseName `json:"license"`
+}
+
+type CycloneDXLicenseName struct {
+	Name string `json:"name"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// newCycloneDXBOM converts the packages into a CycloneDX document
+func newCycloneDXBOM(info *PackagesInfo, timestamp time.Time) *CycloneDXBOM {
+	bom := CycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Tools: CycloneDXTools{Components: []CycloneDXComponent{
+				{Type: "application", Group: sbomToolVendor, Name: sbomToolName},
+			}},
+		},
+		Components: make([]CycloneDXComponent, 0, len(info.Packages)),
+	}
+	if info.Distro != nil {
+		bom.Metadata.Component = &CycloneDXComponent{
+			BOMRef:      "os:" + info.Distro.ID,
+			Type:        "operating-system",
+			Name:        info.Distro.ID,
+			Version:     info.Distro.VersionID,
+			Description: info.Distro.PrettyName,
+		}
+		if info.Hostname != "" {
+			bom.Metadata.Component.Properties = []CycloneDXProperty{{Name: "kubescape:host:hostname", Value: info.Hostname}}
+		}
+	}
+
+	for i := range info.Packages {
+		pkg := &info.Packages[i]
+		component := CycloneDXComponent{
+			BOMRef:    pkg.PURL,
+			Type:      "library",
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Publisher: pkg.Supplier,
+			PURL:      pkg.PURL,
+			Properties: []CycloneDXProperty{
+				{Name: "kubescape:package:type", Value: pkg.Type},
+			},
+		}
+		if pkg.License != "" {
+			component.Licenses = []CycloneDXLicense{{License: CycloneDXLicenseName{Name: pkg.License}}}
+		}
+		if pkg.Source != "" {
+			component.Properties = append(component.Properties, CycloneDXProperty{Name: "kubescape:package:source", Value: pkg.Source})
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	return &bom
+}
+
+// SPDXDocument is an SPDX 2.3 JSON document, see https://spdx.github.io/spdx-spec/v2.3
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+}
+
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type SPDXPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	LicenseComments       string            `json:"licenseComments,omitempty"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// newSPDXDocument converts the packages into an SPDX document.
+// The package licenses are free text in the package databases, so they are reported as license comments.
+func newSPDXDocument(info *PackagesInfo, timestamp time.Time) *SPDXDocument {
+	name := info.Hostname
+	if name == "" {
+		name = sbomToolName
+	}
+	doc := SPDXDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://kubescape.io/spdxdocs/%s/%s-%s", sbomToolName, url.PathEscape(name), uuid.NewString()),
+		CreationInfo: SPDXCreationInfo{
+			Created:  timestamp.UTC().Format(time.RFC3339),
+			Creators: []string{"Organization: " + sbomToolVendor, "Tool: " + sbomToolName},
+		},
+		Packages:      make([]SPDXPackage, 0, len(info.Packages)+1),
+		Relationships: make([]SPDXRelationship, 0, len(info.Packages)+1),
+	}
+
+	// the packages are contained in the operating system package, which the document describes
+	parentID, relationshipType := doc.SPDXID, "DESCRIBES"
+	if info.Distro != nil {
+		osPackage := SPDXPackage{
+			Name:                  info.Distro.ID,
+			SPDXID:                "SPDXRef-OperatingSystem",
+			VersionInfo:           info.Distro.VersionID,
+			Supplier:              spdxNoAssertion,
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		}
+		doc.Packages = append(doc.Packages, osPackage)
+		doc.Relationships = append(doc.Relationships, SPDXRelationship{
+			SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: osPackage.SPDXID,
+		})
+		parentID, relationshipType = osPackage.SPDXID, "CONTAINS"
+	}
+
+	for i := range info.Packages {
+		pkg := &info.Packages[i]
+		spdxPackage := SPDXPackage{
+			Name:                  pkg.Name,
+			SPDXID:                fmt.Sprintf("SPDXRef-Package-%s-%s-%d", pkg.Type, spdxIDInvalidChars.ReplaceAllString(pkg.Name, "-"), i),
+			VersionInfo:           pkg.Version,
+			Supplier:              spdxNoAssertion,
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			LicenseComments:       pkg.License,
+			PrimaryPackagePurpose: "LIBRARY",
+			ExternalRefs: []SPDXExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: pkg.PURL},
+			},
+		}
+		if pkg.Supplier != "" {
+			spdxPackage.Supplier = "Organization: " + pkg.Supplier
+		}
+		if pkg.Source != "" {
+			spdxPackage.SourceInfo = fmt.Sprintf("built from the %s source package %s", pkg.Type, pkg.Source)
+		}
+		doc.Packages = append(doc.Packages, spdxPackage)
+		doc.Relationships = append(doc.Relationships, SPDXRelationship{
+			SPDXElementID: parentID, RelationshipType: relationshipType, RelatedSPDXElement: spdxPackage.SPDXID,
+		})
+	}
+	return &doc
+}
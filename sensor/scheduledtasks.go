@@ -0,0 +1,375 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	crontabFileName = "/etc/crontab"
+	cronDDir        = "/etc/cron.d"
+	cronSpoolDir    = "/var/spool/cron"
+	// busybox crond (e.g. Alpine)
+	busyboxCrontabsDir = "/etc/crontabs"
+
+	ScheduledTaskTypeCron  = "cron"
+	ScheduledTaskTypeTimer = "systemd-timer"
+
+	ScheduledTaskRiskWorldWritableFile   = "world-writable file"
+	ScheduledTaskRiskWorldWritableScript = "world-writable script"
+)
+
+// The run-parts dirs of cron, by schedule
+var cronRunPartsDirs = []struct {
+	dir      string
+	schedule string
+}{
+	{"/etc/cron.hourly", "@hourly"},
+	{"/etc/cron.daily", "@daily"},
+	{"/etc/cron.weekly", "@weekly"},
+	{"/etc/cron.monthly", "@monthly"},
+}
+
+// The access control files of cron, which are reported as sources without tasks
+var cronAccessFileNames = []string{"/etc/cron.allow", "/etc/cron.deny"}
+
+// The at job spools, which may be found under the cron spool dir
+var cronSpoolSkippedDirs = map[string]bool{"atjobs": true, "atspool": true}
+
+// The timer directives which trigger the timer
+var systemdTimerTriggers = []string{"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec", "OnUnitInactiveSec", "OnCalendar"}
+
+// cron and run-parts only run files with these names, e.g. files with a dot (such as .dpkg-old) are skipped
+var cronFileNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// a `NAME=value` line of a crontab
+var crontabEnvRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+
+// ScheduledTask holds a cron entry or a systemd timer
+type ScheduledTask struct {
+	// cron or systemd-timer
+	Type string `json:"type"`
+
+	// Example: "*/5 * * * *", "@daily", "OnCalendar=daily; OnBootSec=15min"
+	Schedule string `json:"schedule"`
+
+	User    string `json:"user"`
+	Command string `json:"command"`
+
+	// The file of the entry
+	Source string `json:"source"`
+
+	// The files referenced by the command which are world-writable
+	WorldWritableScripts []string `json:"worldWritableScripts,omitempty"`
+
+	Risks []string `json:"risks,omitempty"`
+}
+
+// ScheduledTaskSource holds the file info of a crontab, a run-parts script or a timer unit, and its risks
+type ScheduledTaskSource struct {
+	*ds.FileInfo
+
+	Risks []string `json:"risks,omitempty"`
+}
+
+// ScheduledTasksInfo holds the scheduled tasks of the host
+type ScheduledTasksInfo struct {
+	Sources []ScheduledTaskSource `json:"sources"`
+	Tasks   []ScheduledTask       `json:"tasks"`
+}
+
+// cutFields splits the first `n` whitespace separated fields of `line`, returning them and the rest of the line
+func cutFields(line string, n int) ([]string, string) {
+	var fields []string
+	rest := strings.TrimSpace(line)
+	for len(fields) < n && rest != "" {
+		idx := strings.IndexAny(rest, " \t")
+		if idx < 0 {
+			fields = append(fields, rest)
+			rest = ""
+			break
+		}
+		fields = append(fields, rest[:idx])
+		rest = strings.TrimSpace(rest[idx:])
+	}
+	return fields, rest
+}
+
+// parseCrontab parses the entries of a crontab. System crontabs (/etc/crontab and /etc/cron.d) have a user field,
+// a user crontab has the user `user`.
+func parseCrontab(content []byte, source string, user string) []ScheduledTask {
+	var tasks []ScheduledTask
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || crontabEnvRegexp.MatchString(line) {
+			continue
+		}
+
+		// `@reboot`, `@daily`, etc. replace the 5 time fields
+		scheduleFields := 5
+		if strings.HasPrefix(line, "@") {
+			scheduleFields = 1
+		}
+		userFields := 0
+		if user == "" {
+			userFields = 1
+		}
+		fields, command := cutFields(line, scheduleFields+userFields)
+		if len(fields) < scheduleFields+userFields || command == "" {
+			continue
+		}
+
+		task := ScheduledTask{
+			Type:     ScheduledTaskTypeCron,
+			Schedule: strings.Join(fields[:scheduleFields], " "),
+			User:     user,
+			Command:  command,
+			Source:   source,
+		}
+		if user == "" {
+			task.User = fields[scheduleFields]
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// commandPaths returns the absolute paths referenced by a command
+func commandPaths(command string) []string {
+	var paths []string
+	for _, token := range strings.FieldsFunc(command, func(r rune) bool {
+		return strings.ContainsRune(" \t;|&<>()`'\"=", r)
+	}) {
+		if len(token) > 1 && strings.HasPrefix(token, "/") && !strings.HasPrefix(token, "/dev/") {
+			paths = append(paths, path.Clean(token))
+		}
+	}
+	return paths
+}
+
+// worldWritableScripts returns the files referenced by a command which are world-writable on the host.
+// Symlinks are resolved in `rootDir`, so a link to an absolute path is checked against the host file.
+func worldWritableScripts(rootDir, command string) []string {
+	var scripts []string
+	for _, filePath := range commandPaths(command) {
+		stat, err := os.Stat(hostFilePath(rootDir, filePath))
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+		if stat.Mode().Perm()&0o002 != 0 {
+			scripts = append(scripts, filePath)
+		}
+	}
+	return scripts
+}
+
+// scheduledTasksCollector collects the scheduled tasks of the host file system at `rootDir`
+type scheduledTasksCollector struct {
+	ctx     context.Context
+	rootDir string
+	info    *ScheduledTasksInfo
+}
+
+// addSource adds the file info of a source, returning it (nil if it could not be made).
+// The file info of a symlink is of its target in the host file system.
+func (c *scheduledTasksCollector) addSource(filePath string) *ds.FileInfo {
+	fileInfo := makeChangedRootFileInfoVerbose(c.ctx, c.rootDir, resolveHostLink(c.rootDir, filePath), false,
+		helpers.String("in", "SenseScheduledTasks"))
+	if fileInfo == nil {
+		return nil
+	}
+	fileInfo.Path = filePath
+	source := ScheduledTaskSource{FileInfo: fileInfo}
+	if fileInfo.Permissions&0o002 != 0 {
+		source.Risks = append(source.Risks, ScheduledTaskRiskWorldWritableFile)
+	}
+	c.info.Sources = append(c.info.Sources, source)
+	return fileInfo
+}
+
+// addTasks adds tasks, flagging the world-writable scripts they reference
+func (c *scheduledTasksCollector) addTasks(tasks ...ScheduledTask) {
+	for _, task := range tasks {
+		task.WorldWritableScripts = worldWritableScripts(c.rootDir, task.Command)
+		if len(task.WorldWritableScripts) > 0 {
+			task.Risks = append(task.Risks, ScheduledTaskRiskWorldWritableScript)
+		}
+		c.info.Tasks = append(c.info.Tasks, task)
+	}
+}
+
+// addCrontab adds a crontab and its entries
+func (c *scheduledTasksCollector) addCrontab(filePath, user string) {
+	content, err := readHostConfFile(c.rootDir, filePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.L().Ctx(c.ctx).Warning("failed to read crontab", helpers.String("path", filePath), helpers.Error(err))
+		}
+		return
+	}
+	c.addSource(filePath)
+	c.addTasks(parseCrontab(content, filePath, user)...)
+}
+
+// dirFileNames returns the names of the files of a host dir which cron would read
+func (c *scheduledTasksCollector) dirFileNames(dir string) []string {
+	entries, err := os.ReadDir(path.Join(c.rootDir, dir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && cronFileNameRegexp.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func (c *scheduledTasksCollector) collectCron() {
+	c.addCrontab(crontabFileName, "")
+	for _, name := range c.dirFileNames(cronDDir) {
+		c.addCrontab(path.Join(cronDDir, name), "")
+	}
+
+	// run-parts runs the executable files of the dirs as root
+	for _, runParts := range cronRunPartsDirs {
+		for _, name := range c.dirFileNames(runParts.dir) {
+			filePath := path.Join(runParts.dir, name)
+			fileInfo := c.addSource(filePath)
+			if fileInfo == nil || fileInfo.Permissions&0o111 == 0 {
+				continue
+			}
+			c.addTasks(ScheduledTask{
+				Type:     ScheduledTaskTypeCron,
+				Schedule: runParts.schedule,
+				User:     "root",
+				Command:  filePath,
+				Source:   filePath,
+			})
+		}
+	}
+
+	for _, accessFileName := range cronAccessFileNames {
+		if _, err := os.Stat(hostFilePath(c.rootDir, accessFileName)); err == nil {
+			c.addSource(accessFileName)
+		}
+	}
+
+	// user crontabs are named after their user, e.g. /var/spool/cron/crontabs/alice (Debian) or /var/spool/cron/alice (RHEL)
+	spoolDir := path.Join(c.rootDir, cronSpoolDir)
+	err := fs.WalkDir(os.DirFS(spoolDir), ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if cronSpoolSkippedDirs[entry.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			c.addCrontab(path.Join(cronSpoolDir, filePath), entry.Name())
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		logger.L().Ctx(c.ctx).Warning("failed to walk the cron spool", helpers.Error(err))
+	}
+
+	for _, name := range c.dirFileNames(busyboxCrontabsDir) {
+		c.addCrontab(path.Join(busyboxCrontabsDir, name), name)
+	}
+}
+
+// systemdTimerTask returns the task of a timer unit, which runs the ExecStart of its service unit
+func (c *scheduledTasksCollector) systemdTimerTask(timerPath string) (*ScheduledTask, error) {
+	name := path.Base(timerPath)
+	timer, err := readSystemdUnitFile(c.rootDir, timerPath, systemdDropInPaths(c.rootDir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule []string
+	for _, trigger := range systemdTimerTriggers {
+		for _, value := range timer.values("Timer", trigger) {
+			schedule = append(schedule, trigger+"="+value)
+		}
+	}
+	task := ScheduledTask{
+		Type:     ScheduledTaskTypeTimer,
+		Schedule: strings.Join(schedule, "; "),
+		User:     "root",
+		Source:   timerPath,
+	}
+
+	serviceName := timer.value("Timer", "Unit")
+	if serviceName == "" {
+		serviceName = strings.TrimSuffix(name, ".timer") + ".service"
+	}
+	serviceFileNames := make([]string, 0, len(systemdUnitDirs))
+	for _, dir := range systemdUnitDirs {
+		serviceFileNames = append(serviceFileNames, path.Join(dir, serviceName))
+	}
+	if servicePath, _ := existingHostFile(c.rootDir, serviceFileNames...); servicePath != "" {
+		service, err := readSystemdUnitFile(c.rootDir, servicePath, systemdDropInPaths(c.rootDir, serviceName))
+		if err != nil {
+			return nil, err
+		}
+		if user := service.value("Service", "User"); user != "" {
+			task.User = user
+		}
+		var commands []string
+		for _, command := range service.values("Service", "ExecStart") {
+			// drop the special executable prefixes, e.g. `-` to ignore failures
+			commands = append(commands, strings.TrimLeft(command, "-@:+!|"))
+		}
+		task.Command = strings.Join(commands, "; ")
+	}
+	return &task, nil
+}
+
+func (c *scheduledTasksCollector) collectSystemdTimers() {
+	for _, timerPath := range overlayConfFiles(c.rootDir, systemdUnitDirs, ".timer") {
+		// masked
+		if target, err := os.Readlink(path.Join(c.rootDir, timerPath)); err == nil && target == "/dev/null" {
+			continue
+		}
+		task, err := c.systemdTimerTask(timerPath)
+		if err != nil {
+			logger.L().Ctx(c.ctx).Warning("failed to read timer unit", helpers.String("path", timerPath), helpers.Error(err))
+			continue
+		}
+		c.addSource(timerPath)
+		c.addTasks(*task)
+	}
+}
+
+func senseScheduledTasks(ctx context.Context, rootDir string) (*ScheduledTasksInfo, error) {
+	collector := scheduledTasksCollector{
+		ctx:     ctx,
+		rootDir: rootDir,
+		info:    &ScheduledTasksInfo{Sources: []ScheduledTaskSource{}, Tasks: []ScheduledTask{}},
+	}
+	collector.collectCron()
+	collector.collectSystemdTimers()
+	return collector.info, nil
+}
+
+// SenseScheduledTasks returns the cron entries and the systemd timers of the host,
+// with the file info of their sources and the world-writable files they run.
+func SenseScheduledTasks(ctx context.Context) (*ScheduledTasksInfo, error) {
+	return senseScheduledTasks(ctx, utils.HostFileSystemDefaultLocation)
+}
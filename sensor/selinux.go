@@ -0,0 +1,91 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"strings"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	seLinuxFsDir          = "/sys/fs/selinux"
+	seLinuxConfigFileName = "/etc/selinux/config"
+
+	seLinuxEnforcing  = "enforcing"
+	seLinuxPermissive = "permissive"
+	seLinuxDisabled   = "disabled"
+)
+
+// processSecurityLabels returns the `lsm` labels of the kubelet and the container runtime processes
+func processSecurityLabels(procDir, lsm string) []ds.ProcessSecurityLabel {
+	res := make([]ds.ProcessSecurityLabel, 0)
+	for _, proc := range findNodeCriticalProcesses(procDir) {
+		label, err := processSecurityLabel(procDir, proc.pid, lsm)
+		if err != nil || label == "" {
+			continue
+		}
+		res = append(res, ds.ProcessSecurityLabel{Name: proc.name, PID: proc.pid, Label: label})
+	}
+	return res
+}
+
+// parseSELinuxConfig returns the SELINUX and SELINUXTYPE values of an /etc/selinux/config file
+func parseSELinuxConfig(content []byte) (mode, policyType string) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		val = strings.Trim(strings.TrimSpace(val), `"'`)
+		switch strings.TrimSpace(key) {
+		case "SELINUX":
+			mode = strings.ToLower(val)
+		case "SELINUXTYPE":
+			policyType = val
+		}
+	}
+	return mode, policyType
+}
+
+// senseSELinux reads the SELinux state of the host file system at `rootDir`, and the process labels under `procDir`
+func senseSELinux(rootDir, procDir string) *ds.SELinuxStatus {
+	res := ds.SELinuxStatus{Mode: seLinuxDisabled}
+
+	if enforce, err := os.ReadFile(path.Join(rootDir, seLinuxFsDir, "enforce")); err == nil {
+		res.Enabled = true
+		res.Mode = seLinuxPermissive
+		if strings.TrimSpace(string(enforce)) == "1" {
+			res.Mode = seLinuxEnforcing
+		}
+		if policyVers, err := os.ReadFile(path.Join(rootDir, seLinuxFsDir, "policyvers")); err == nil {
+			res.PolicyVersion = strings.TrimSpace(string(policyVers))
+		}
+		res.ProcessContexts = processSecurityLabels(procDir, "selinux")
+	}
+
+	if config, err := os.ReadFile(path.Join(rootDir, seLinuxConfigFileName)); err == nil {
+		res.ConfigMode, res.PolicyType = parseSELinuxConfig(config)
+		agree := res.ConfigMode == res.Mode
+		res.ModesAgree = &agree
+	}
+
+	return &res
+}
+
+// getSELinuxStatus returns the SELinux mode, and the structured SELinux status if SELinux is enabled or configured
+func getSELinuxStatus() (string, *ds.SELinuxStatus) {
+	status := senseSELinux(utils.HostFileSystemDefaultLocation, procDirName)
+	if !status.Enabled && status.ModesAgree == nil {
+		return "not found", nil
+	}
+	return status.Mode, status
+}
@@ -0,0 +1,37 @@
+package sensor
+
+import (
+	"testing"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSELinuxConfig(t *testing.T) {
+	mode, policyType := parseSELinuxConfig([]byte("# comment\nSELINUX = Permissive\nSELINUXTYPE=\"mls\"\n"))
+	assert.Equal(t, "permissive", mode)
+	assert.Equal(t, "mls", policyType)
+}
+
+func Test_senseSELinux(t *testing.T) {
+	status := senseSELinux("testdata/selinux/root", "testdata/selinux/proc")
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "permissive", status.Mode)
+	assert.Equal(t, "enforcing", status.ConfigMode)
+	assert.Equal(t, "targeted", status.PolicyType)
+	assert.Equal(t, "33", status.PolicyVersion)
+	require.NotNil(t, status.ModesAgree)
+	assert.False(t, *status.ModesAgree)
+	assert.Equal(t, []ds.ProcessSecurityLabel{
+		{Name: "kubelet", PID: 300, Label: "system_u:system_r:kubelet_t:s0"},
+		{Name: "containerd", PID: 301, Label: "system_u:system_r:container_runtime_t:s0"},
+	}, status.ProcessContexts)
+
+	// no selinuxfs and no config
+	status = senseSELinux("testdata/selinux/missing", "testdata/selinux/proc")
+	assert.False(t, status.Enabled)
+	assert.Equal(t, "disabled", status.Mode)
+	assert.Nil(t, status.ModesAgree)
+	assert.Empty(t, status.ProcessContexts)
+}
@@ -0,0 +1,275 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	sshdConfigDir      = "/etc/ssh"
+	sshdConfigFileName = "/etc/ssh/sshd_config"
+
+	// the maximal depth of nested Include directives, as in sshd
+	sshdMaxIncludeDepth = 16
+)
+
+// sshd keywords which accumulate their values, instead of the first value winning
+var sshdMultiValueKeywords = []string{
+	"acceptenv", "allowgroups", "allowusers", "denygroups", "denyusers", "hostcertificate", "hostkey",
+	"listenaddress", "permitlisten", "permitopen", "port", "setenv", "subsystem",
+}
+
+// The defaults of the security relevant sshd settings, see sshd_config(5)
+var sshdDefaultSettings = map[string]string{
+	"port":                         "22",
+	"permitrootlogin":              "prohibit-password",
+	"passwordauthentication":       "yes",
+	"permitemptypasswords":         "no",
+	"pubkeyauthentication":         "yes",
+	"kbdinteractiveauthentication": "yes",
+	"hostbasedauthentication":      "no",
+	"ignorerhosts":                 "yes",
+	"permituserenvironment":        "no",
+	"x11forwarding":                "no",
+	"allowtcpforwarding":           "yes",
+	"allowagentforwarding":         "yes",
+	"gatewayports":                 "no",
+	"permittunnel":                 "no",
+	"maxauthtries":                 "6",
+	"maxsessions":                  "10",
+	"maxstartups":                  "10:30:100",
+	"logingracetime":               "120",
+	"clientaliveinterval":          "0",
+	"clientalivecountmax":          "3",
+	"loglevel":                     "INFO",
+	"usepam":                       "no",
+	"strictmodes":                  "yes",
+	"disableforwarding":            "no",
+	"authorizedkeysfile":           ".ssh/authorized_keys .ssh/authorized_keys2",
+	"ciphers":                      "chacha20-poly1305@openssh.com,aes128-ctr,aes192-ctr,aes256-ctr,aes128-gcm@openssh.com,aes256-gcm@openssh.com",
+	"macs":                         "umac-64-etm@openssh.com,umac-128-etm@openssh.com,hmac-sha2-256-etm@openssh.com,hmac-sha2-512-etm@openssh.com,hmac-sha1-etm@openssh.com,umac-64@openssh.com,umac-128@openssh.com,hmac-sha2-256,hmac-sha2-512,hmac-sha1",
+}
+
+// The host keys sshd loads when no HostKey is set
+var sshdDefaultHostKeys = []string{
+	"/etc/ssh/ssh_host_rsa_key",
+	"/etc/ssh/ssh_host_ecdsa_key",
+	"/etc/ssh/ssh_host_ed25519_key",
+}
+
+// SSHDMatchBlock holds the settings of a Match block
+type SSHDMatchBlock struct {
+	// Example: User backup Address 10.0.0.0/8
+	Criteria string `json:"criteria"`
+
+	// The config file of the block
+	Path string `json:"path"`
+
+	Settings map[string][]string `json:"settings"`
+}
+
+// SSHDInfo holds the effective configuration of the SSH daemon
+type SSHDInfo struct {
+	// The config files, in the order they were read
+	ConfigFiles []string `json:"configFiles"`
+
+	// The effective global settings, by lower case keyword. Multi value keywords, e.g. AllowUsers, hold a value
+	// per directive, others hold the first value which was set.
+	// Example: {"permitrootlogin": ["no"], "allowusers": ["ubuntu deploy"]}
+	Settings map[string][]string `json:"settings"`
+
+	// The settings which were not set and hold their default value
+	DefaultSettings []string `json:"defaultSettings"`
+
+	MatchBlocks []SSHDMatchBlock `json:"matchBlocks"`
+
+	// The file info of the host keys (never their content)
+	HostKeys []*ds.FileInfo `json:"hostKeys"`
+}
+
+// splitSSHDArgs splits the arguments of a directive on whitespace, keeping double quoted arguments together
+func splitSSHDArgs(args string) []string {
+	res := []string{}
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+	for _, r := range args {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				res = append(res, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		res = append(res, current.String())
+	}
+	return res
+}
+
+// parseSSHDLine returns the lower case keyword and the arguments of a directive. The keyword may be followed
+// by whitespace or `=`.
+func parseSSHDLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+	idx := strings.IndexAny(line, " \t=")
+	if idx == -1 {
+		return strings.ToLower(line), []string{}
+	}
+	keyword := strings.ToLower(line[:idx])
+	rest := strings.TrimLeft(line[idx:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+	return keyword, splitSSHDArgs(rest)
+}
+
+// setSSHDSetting applies sshd's first value wins semantics, and accumulates the values of multi value keywords
+func setSSHDSetting(settings map[string][]string, keyword string, args []string) {
+	value := strings.Join(args, " ")
+	if slices.Contains(sshdMultiValueKeywords, keyword) {
+		settings[keyword] = append(settings[keyword], value)
+	} else if _, set := settings[keyword]; !set {
+		settings[keyword] = []string{value}
+	}
+}
+
+// sshdConfigParser reads an sshd config file of the host file system at `rootDir`, with its included files
+type sshdConfigParser struct {
+	ctx     context.Context
+	rootDir string
+	info    *SSHDInfo
+}
+
+// settings returns the settings of Match block `blockIdx`, or the global settings if -1
+func (p *sshdConfigParser) settings(blockIdx int) map[string][]string {
+	if blockIdx == -1 {
+		return p.info.Settings
+	}
+	return p.info.MatchBlocks[blockIdx].Settings
+}
+
+// parseFile parses `filePath`. Directives are applied to Match block `blockIdx`, or to the global settings if -1.
+// A Match block in the file lasts until the end of the file.
+func (p *sshdConfigParser) parseFile(filePath string, blockIdx int, depth int) error {
+	if depth > sshdMaxIncludeDepth {
+		return fmt.Errorf("too many nested Include directives in %s", filePath)
+	}
+	content, err := readHostConfFile(p.rootDir, filePath)
+	if err != nil {
+		return err
+	}
+	p.info.ConfigFiles = append(p.info.ConfigFiles, filePath)
+
+	for _, line := range strings.Split(string(content), "\n") {
+		keyword, args := parseSSHDLine(line)
+		switch keyword {
+		case "":
+			continue
+		case "match":
+			p.info.MatchBlocks = append(p.info.MatchBlocks, SSHDMatchBlock{
+				Criteria: strings.Join(args, " "),
+				Path:     filePath,
+				Settings: map[string][]string{},
+			})
+			blockIdx = len(p.info.MatchBlocks) - 1
+		case "include":
+			for _, pattern := range args {
+				p.include(pattern, blockIdx, depth)
+			}
+		default:
+			setSSHDSetting(p.settings(blockIdx), keyword, args)
+		}
+	}
+	return nil
+}
+
+// include parses the files which match `pattern`, in lexical order. Relative patterns are relative to /etc/ssh.
+func (p *sshdConfigParser) include(pattern string, blockIdx int, depth int) {
+	if !path.IsAbs(pattern) {
+		pattern = path.Join(sshdConfigDir, pattern)
+	}
+	matches, err := filepath.Glob(path.Join(p.rootDir, pattern))
+	if err != nil {
+		logger.L().Ctx(p.ctx).Warning("In SenseSSHD invalid Include pattern", helpers.String("pattern", pattern), helpers.Error(err))
+		return
+	}
+	sort.Strings(matches)
+	for _, match := range matches {
+		filePath, _ := filepath.Rel(p.rootDir, match)
+		filePath = "/" + filePath
+		if err := p.parseFile(filePath, blockIdx, depth+1); err != nil {
+			logger.L().Ctx(p.ctx).Warning("In SenseSSHD failed to read included file", helpers.String("path", filePath), helpers.Error(err))
+		}
+	}
+}
+
+// senseSSHD reads the sshd config of the host file system at `rootDir`
+func senseSSHD(ctx context.Context, rootDir string) (*SSHDInfo, error) {
+	ret := SSHDInfo{
+		ConfigFiles:     make([]string, 0),
+		Settings:        map[string][]string{},
+		DefaultSettings: make([]string, 0),
+		MatchBlocks:     make([]SSHDMatchBlock, 0),
+		HostKeys:        make([]*ds.FileInfo, 0),
+	}
+
+	parser := sshdConfigParser{ctx: ctx, rootDir: rootDir, info: &ret}
+	if err := parser.parseFile(sshdConfigFileName, -1, 0); err != nil {
+		return &ret, err
+	}
+
+	for keyword, value := range sshdDefaultSettings {
+		if _, set := ret.Settings[keyword]; !set {
+			ret.Settings[keyword] = []string{value}
+			ret.DefaultSettings = append(ret.DefaultSettings, keyword)
+		}
+	}
+	sort.Strings(ret.DefaultSettings)
+
+	hostKeys, set := ret.Settings["hostkey"]
+	if !set {
+		hostKeys = sshdDefaultHostKeys
+	}
+	for _, hostKey := range hostKeys {
+		if !path.IsAbs(hostKey) {
+			hostKey = path.Join(sshdConfigDir, hostKey)
+		}
+		// the file info of a linked host key is of its target in the host file system
+		hostKeyTarget := resolveHostLink(rootDir, hostKey)
+		if _, err := os.Stat(path.Join(rootDir, hostKeyTarget)); err != nil && !set {
+			// not every default key type exists
+			continue
+		}
+		if fileInfo := makeChangedRootFileInfoVerbose(ctx, rootDir, hostKeyTarget, false, helpers.String("in", "SenseSSHD")); fileInfo != nil {
+			fileInfo.Path = hostKey
+			ret.HostKeys = append(ret.HostKeys, fileInfo)
+		}
+	}
+
+	return &ret, nil
+}
+
+// SenseSSHD returns the effective global settings and the Match blocks of the SSH daemon config, following its
+// Include directives, and the file info of the host keys
+func SenseSSHD(ctx context.Context) (*SSHDInfo, error) {
+	return senseSSHD(ctx, utils.HostFileSystemDefaultLocation)
+}
@@ -0,0 +1,61 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSSHDLine(t *testing.T) {
+	keyword, args := parseSSHDLine("  PermitRootLogin=no")
+	assert.Equal(t, "permitrootlogin", keyword)
+	assert.Equal(t, []string{"no"}, args)
+
+	keyword, args = parseSSHDLine(`Subsystem sftp "/usr/lib/sftp server" -l INFO`)
+	assert.Equal(t, "subsystem", keyword)
+	assert.Equal(t, []string{"sftp", "/usr/lib/sftp server", "-l", "INFO"}, args)
+
+	keyword, _ = parseSSHDLine("# PermitRootLogin yes")
+	assert.Equal(t, "", keyword)
+}
+
+func Test_senseSSHD(t *testing.T) {
+	info, err := senseSSHD(context.TODO(), "testdata/sshd/root")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/etc/ssh/sshd_config",
+		"/etc/ssh/sshd_config.d/10-hardening.conf",
+		"/etc/ssh/sshd_config.d/50-cloud-init.conf",
+		"/etc/ssh/sshd_config.d/60-managed.conf",
+		"/etc/ssh/match.d/x11.conf",
+	}, info.ConfigFiles)
+
+	// the drop-ins are included before the main file settings, so their values win
+	assert.Equal(t, []string{"no"}, info.Settings["permitrootlogin"])
+	assert.Equal(t, []string{"no"}, info.Settings["passwordauthentication"])
+	assert.Equal(t, []string{"hmac-sha2-512-etm@openssh.com"}, info.Settings["macs"])
+	assert.Equal(t, []string{"aes256-gcm@openssh.com,aes128-ctr"}, info.Settings["ciphers"])
+	assert.Equal(t, []string{"ubuntu", "deploy"}, info.Settings["allowusers"])
+	assert.Equal(t, []string{"sftp /usr/lib/openssh/sftp-server -l INFO"}, info.Settings["subsystem"])
+	assert.Equal(t, []string{"no"}, info.Settings["x11forwarding"])
+	// 60-managed.conf is an absolute link, resolved in the host file system
+	assert.Equal(t, []string{"3"}, info.Settings["maxauthtries"])
+	assert.Contains(t, info.DefaultSettings, "x11forwarding")
+	assert.NotContains(t, info.DefaultSettings, "permitrootlogin")
+
+	require.Len(t, info.MatchBlocks, 3)
+	assert.Equal(t, SSHDMatchBlock{Criteria: "Group sftp", Path: "/etc/ssh/sshd_config.d/10-hardening.conf",
+		Settings: map[string][]string{"forcecommand": {"internal-sftp"}}}, info.MatchBlocks[0])
+	assert.Equal(t, SSHDMatchBlock{Criteria: "User backup", Path: "/etc/ssh/sshd_config",
+		Settings: map[string][]string{"passwordauthentication": {"yes"}, "x11forwarding": {"yes"}}}, info.MatchBlocks[1])
+	assert.Equal(t, "Address 10.0.0.0/8", info.MatchBlocks[2].Criteria)
+
+	require.Len(t, info.HostKeys, 2)
+	assert.Equal(t, "/etc/ssh/ssh_host_ed25519_key", info.HostKeys[0].Path)
+	// ssh_host_rsa_key is an absolute link to /var/lib/ssh/ssh_host_rsa_key
+	assert.Equal(t, "/etc/ssh/ssh_host_rsa_key", info.HostKeys[1].Path)
+	assert.Nil(t, info.HostKeys[0].Content)
+}
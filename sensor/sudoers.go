@@ -0,0 +1,386 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	sudoersFileName = "/etc/sudoers"
+
+	// the maximal depth of nested include directives, as in sudo
+	sudoersMaxIncludeDepth = 128
+
+	// sudoers risks
+	SudoersRiskNoPasswdAll     = "NOPASSWD: ALL"
+	SudoersRiskAllCommands     = "ALL commands"
+	SudoersRiskWildcardCommand = "wildcard in command path"
+	SudoersRiskWildcardArgs    = "wildcard in command arguments"
+	SudoersRiskNoAuthenticate  = "!authenticate"
+	SudoersRiskWritableFile    = "file writable by non-root"
+	SudoersRiskNonRootOwner    = "file not owned by root"
+)
+
+var (
+	sudoersAliasTypes = []string{"User_Alias", "Runas_Alias", "Host_Alias", "Cmnd_Alias", "Cmd_Alias"}
+
+	// a tag of a command, e.g. `NOPASSWD:`
+	sudoersTagRegex = regexp.MustCompile(`^([A-Z_]+):\s*`)
+)
+
+// SudoersDefault holds a Defaults entry
+type SudoersDefault struct {
+	Path string `json:"path"`
+
+	// The binding of the entry, e.g. `:admin` or `>root`, empty for global defaults
+	Binding string `json:"binding,omitempty"`
+
+	// Example: ["env_reset", "!authenticate"]
+	Settings []string `json:"settings"`
+
+	Risks []string `json:"risks,omitempty"`
+}
+
+// SudoersAlias holds an alias definition
+type SudoersAlias struct {
+	Path string `json:"path"`
+
+	// User_Alias, Runas_Alias, Host_Alias or Cmnd_Alias
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// SudoersCommand holds a command of a user specification, with its run-as spec and tags
+type SudoersCommand struct {
+	// Example: /usr/bin/systemctl restart kubelet
+	Command string `json:"command"`
+
+	// Example: root:docker. Empty for the default (root).
+	RunAs string `json:"runAs,omitempty"`
+
+	// Example: ["NOPASSWD"]
+	Tags []string `json:"tags,omitempty"`
+}
+
+// SudoersRule holds a user specification
+type SudoersRule struct {
+	Path string `json:"path"`
+
+	// Users, %groups and User_Aliases
+	Users    []string         `json:"users"`
+	Hosts    []string         `json:"hosts"`
+	Commands []SudoersCommand `json:"commands"`
+
+	Risks []string `json:"risks,omitempty"`
+}
+
+// SudoersFile holds the file info of a sudoers file and its risks
+type SudoersFile struct {
+	*ds.FileInfo
+
+	Risks []string `json:"risks,omitempty"`
+}
+
+// SudoersInfo holds the parsed sudoers configuration of the host
+type SudoersInfo struct {
+	Files    []SudoersFile    `json:"files"`
+	Defaults []SudoersDefault `json:"defaults"`
+	Aliases  []SudoersAlias   `json:"aliases"`
+	Rules    []SudoersRule    `json:"rules"`
+}
+
+// splitSudoersList splits a comma separated list, ignoring commas inside parentheses and escaped commas
+func splitSudoersList(list string) []string {
+	res := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); last != "" {
+		res = append(res, last)
+	}
+	return res
+}
+
+// stripSudoersComment removes the comment at the end of a line. A `#` followed by digits is a user ID,
+// e.g. `#0 ALL=(ALL) ALL`, and not a comment.
+func stripSudoersComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '#':
+			if i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+				continue
+			}
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return line
+}
+
+// sudoersLines returns the logical lines of a sudoers file, joining continuation lines
+func sudoersLines(content string) []string {
+	res := []string{}
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		if trimmed := strings.TrimRight(line, " \t"); strings.HasSuffix(trimmed, "\\") {
+			current.WriteString(strings.TrimSuffix(trimmed, "\\"))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(line)
+		res = append(res, strings.TrimSpace(current.String()))
+		current.Reset()
+	}
+	return res
+}
+
+// parseSudoersCommands parses the command list of a user specification.
+// The run-as spec and the tags of a command apply to the next commands of the list, unless they set their own.
+func parseSudoersCommands(list string) []SudoersCommand {
+	res := []SudoersCommand{}
+	runAs := ""
+	tags := map[string]string{}
+	for _, item := range splitSudoersList(list) {
+		if strings.HasPrefix(item, "(") {
+			if end := strings.Index(item, ")"); end != -1 {
+				runAs = strings.TrimSpace(item[1:end])
+				item = strings.TrimSpace(item[end+1:])
+			}
+		}
+		for {
+			match := sudoersTagRegex.FindStringSubmatch(item)
+			if match == nil {
+				break
+			}
+			tag := match[1]
+			// a tag overrides its negation, e.g. PASSWD and NOPASSWD
+			key := strings.TrimPrefix(tag, "NO")
+			tags[key] = tag
+			item = item[len(match[0]):]
+		}
+		command := SudoersCommand{Command: strings.TrimSpace(item), RunAs: runAs}
+		for _, tag := range tags {
+			command.Tags = append(command.Tags, tag)
+		}
+		sort.Strings(command.Tags)
+		res = append(res, command)
+	}
+	return res
+}
+
+// sudoersRuleRisks returns the risky patterns of a user specification
+func sudoersRuleRisks(rule *SudoersRule) []string {
+	var risks []string
+	addRisk := func(risk string) {
+		for _, r := range risks {
+			if r == risk {
+				return
+			}
+		}
+		risks = append(risks, risk)
+	}
+	for _, command := range rule.Commands {
+		cmdPath, args, _ := strings.Cut(command.Command, " ")
+		if command.Command == "ALL" {
+			addRisk(SudoersRiskAllCommands)
+			for _, tag := range command.Tags {
+				if tag == "NOPASSWD" {
+					addRisk(SudoersRiskNoPasswdAll)
+				}
+			}
+		}
+		if strings.ContainsAny(cmdPath, "*?[") {
+			addRisk(SudoersRiskWildcardCommand)
+		}
+		if strings.ContainsAny(args, "*?[") {
+			addRisk(SudoersRiskWildcardArgs)
+		}
+	}
+	return risks
+}
+
+// sudoersFileRisks returns the risks of the permissions and ownership of a sudoers file
+func sudoersFileRisks(fileInfo *ds.FileInfo) []string {
+	var risks []string
+	if fileInfo.Permissions&0o022 != 0 {
+		risks = append(risks, SudoersRiskWritableFile)
+	}
+	if fileInfo.Ownership != nil && fileInfo.Ownership.Err == "" && fileInfo.Ownership.UID != 0 {
+		risks = append(risks, SudoersRiskNonRootOwner)
+	}
+	return risks
+}
+
+// sudoersParser reads the sudoers files of the host file system at `rootDir`
+type sudoersParser struct {
+	ctx     context.Context
+	rootDir string
+	info    *SudoersInfo
+}
+
+func (p *sudoersParser) parseFile(filePath string, depth int) error {
+	if depth > sudoersMaxIncludeDepth {
+		return fmt.Errorf("too many nested includes in %s", filePath)
+	}
+	content, err := os.ReadFile(path.Join(p.rootDir, filePath))
+	if err != nil {
+		return err
+	}
+	if fileInfo := makeChangedRootFileInfoVerbose(p.ctx, p.rootDir, filePath, false, helpers.String("in", "SenseSudoers")); fileInfo != nil {
+		p.info.Files = append(p.info.Files, SudoersFile{FileInfo: fileInfo, Risks: sudoersFileRisks(fileInfo)})
+	}
+
+	for _, line := range sudoersLines(string(content)) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "#include", "@include":
+			if len(fields) > 1 {
+				p.include(filePath, fields[1], false, depth)
+			}
+			continue
+		case "#includedir", "@includedir":
+			if len(fields) > 1 {
+				p.include(filePath, fields[1], true, depth)
+			}
+			continue
+		}
+		if line = stripSudoersComment(line); line == "" {
+			continue
+		}
+		p.parseLine(filePath, line)
+	}
+	return nil
+}
+
+// include parses the included file, or the files of the included dir. Relative paths are relative to the
+// including file. As sudo does, files in an included dir which end with `~` or contain a `.` are skipped.
+func (p *sudoersParser) include(filePath, target string, isDir bool, depth int) {
+	if !path.IsAbs(target) {
+		target = path.Join(path.Dir(filePath), target)
+	}
+	if !isDir {
+		if err := p.parseFile(target, depth+1); err != nil {
+			logger.L().Ctx(p.ctx).Warning("In SenseSudoers failed to read included file", helpers.String("path", target), helpers.Error(err))
+		}
+		return
+	}
+
+	entries, err := os.ReadDir(path.Join(p.rootDir, target))
+	if err != nil {
+		logger.L().Ctx(p.ctx).Debug("In SenseSudoers failed to read included dir", helpers.String("path", target), helpers.Error(err))
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), "~") || strings.Contains(entry.Name(), ".") {
+			continue
+		}
+		if err := p.parseFile(path.Join(target, entry.Name()), depth+1); err != nil {
+			logger.L().Ctx(p.ctx).Warning("In SenseSudoers failed to read included file", helpers.String("path", entry.Name()), helpers.Error(err))
+		}
+	}
+}
+
+// parseLine parses a Defaults entry, an alias definition or a user specification
+func (p *sudoersParser) parseLine(filePath, line string) {
+	keyword := strings.Fields(line)[0]
+	rest := strings.TrimSpace(line[len(keyword):])
+
+	// Defaults, Defaults:user, Defaults@host, Defaults>runas or Defaults!command
+	if binding, ok := strings.CutPrefix(keyword, "Defaults"); ok {
+		entry := SudoersDefault{Path: filePath, Binding: binding, Settings: splitSudoersList(rest)}
+		for _, setting := range entry.Settings {
+			if strings.ReplaceAll(setting, " ", "") == SudoersRiskNoAuthenticate {
+				entry.Risks = append(entry.Risks, SudoersRiskNoAuthenticate)
+			}
+		}
+		p.info.Defaults = append(p.info.Defaults, entry)
+		return
+	}
+
+	for _, aliasType := range sudoersAliasTypes {
+		if keyword != aliasType {
+			continue
+		}
+		// multiple aliases of the same type are separated by `:`
+		for _, def := range strings.Split(rest, ":") {
+			name, members, ok := strings.Cut(def, "=")
+			if !ok {
+				continue
+			}
+			p.info.Aliases = append(p.info.Aliases, SudoersAlias{
+				Path:    filePath,
+				Type:    aliasType,
+				Name:    strings.TrimSpace(name),
+				Members: splitSudoersList(members),
+			})
+		}
+		return
+	}
+
+	// user_list host_list = cmnd_spec_list
+	specs, commands, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+	specFields := strings.Fields(specs)
+	if len(specFields) < 2 {
+		return
+	}
+	rule := SudoersRule{
+		Path:     filePath,
+		Users:    splitSudoersList(strings.Join(specFields[:len(specFields)-1], " ")),
+		Hosts:    splitSudoersList(specFields[len(specFields)-1]),
+		Commands: parseSudoersCommands(commands),
+	}
+	rule.Risks = sudoersRuleRisks(&rule)
+	p.info.Rules = append(p.info.Rules, rule)
+}
+
+// senseSudoers reads /etc/sudoers and its included files of the host file system at `rootDir`
+func senseSudoers(ctx context.Context, rootDir string) (*SudoersInfo, error) {
+	ret := SudoersInfo{
+		Files:    make([]SudoersFile, 0),
+		Defaults: make([]SudoersDefault, 0),
+		Aliases:  make([]SudoersAlias, 0),
+		Rules:    make([]SudoersRule, 0),
+	}
+	parser := sudoersParser{ctx: ctx, rootDir: rootDir, info: &ret}
+	if err := parser.parseFile(sudoersFileName, 0); err != nil {
+		return &ret, err
+	}
+	return &ret, nil
+}
+
+// SenseSudoers returns the sudoers Defaults, aliases and user specifications of /etc/sudoers and its included
+// files, with their risky patterns, and the permissions of each sudoers file
+func SenseSudoers(ctx context.Context) (*SudoersInfo, error) {
+	return senseSudoers(ctx, utils.HostFileSystemDefaultLocation)
+}
@@ -0,0 +1,68 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSudoersCommands(t *testing.T) {
+	commands := parseSudoersCommands(" (root) NOPASSWD: /usr/bin/systemctl restart *, KUBE, (www-data) /usr/bin/less, PASSWD: NOEXEC: /usr/bin/journalctl")
+	assert.Equal(t, []SudoersCommand{
+		{Command: "/usr/bin/systemctl restart *", RunAs: "root", Tags: []string{"NOPASSWD"}},
+		{Command: "KUBE", RunAs: "root", Tags: []string{"NOPASSWD"}},
+		{Command: "/usr/bin/less", RunAs: "www-data", Tags: []string{"NOPASSWD"}},
+		{Command: "/usr/bin/journalctl", RunAs: "www-data", Tags: []string{"NOEXEC", "PASSWD"}},
+	}, commands)
+}
+
+func Test_sudoersFileRisks(t *testing.T) {
+	assert.Empty(t, sudoersFileRisks(&ds.FileInfo{Permissions: 0o440, Ownership: &ds.FileOwnership{UID: 0}}))
+	assert.Equal(t, []string{SudoersRiskWritableFile, SudoersRiskNonRootOwner},
+		sudoersFileRisks(&ds.FileInfo{Permissions: 0o666, Ownership: &ds.FileOwnership{UID: 1000}}))
+}
+
+func Test_senseSudoers(t *testing.T) {
+	info, err := senseSudoers(context.TODO(), "testdata/sudoers/root")
+	require.NoError(t, err)
+
+	// old.bak is skipped by @includedir
+	paths := []string{}
+	for _, file := range info.Files {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{"/etc/sudoers", "/etc/sudoers.d/90-cloud-init-users", "/etc/sudoers.d/95-temp", "/etc/sudoers.d/99-ops", "/etc/sudoers.d/README"}, paths)
+
+	require.Len(t, info.Defaults, 3)
+	assert.Equal(t, SudoersDefault{Path: "/etc/sudoers", Binding: ":deploy", Settings: []string{"!authenticate", "!requiretty"},
+		Risks: []string{SudoersRiskNoAuthenticate}}, info.Defaults[2])
+
+	assert.Equal(t, []SudoersAlias{
+		{Path: "/etc/sudoers", Type: "Cmnd_Alias", Name: "KUBE", Members: []string{"/usr/bin/kubectl", "/usr/bin/crictl"}},
+		{Path: "/etc/sudoers", Type: "Cmnd_Alias", Name: "RESTART", Members: []string{"/usr/bin/systemctl restart kubelet"}},
+	}, info.Aliases)
+
+	require.Len(t, info.Rules, 8)
+	assert.Equal(t, SudoersRule{
+		Path:     "/etc/sudoers",
+		Users:    []string{"%admin"},
+		Hosts:    []string{"ALL"},
+		Commands: []SudoersCommand{{Command: "ALL", RunAs: "ALL"}},
+		Risks:    []string{SudoersRiskAllCommands},
+	}, info.Rules[1])
+	assert.Equal(t, []string{"ubuntu"}, info.Rules[3].Users)
+	assert.Equal(t, []string{SudoersRiskAllCommands, SudoersRiskNoPasswdAll}, info.Rules[3].Risks)
+	// a user ID is not a comment, and a trailing comment is not a part of the command
+	assert.Equal(t, []string{"#0"}, info.Rules[4].Users)
+	assert.Equal(t, []string{SudoersRiskAllCommands, SudoersRiskNoPasswdAll}, info.Rules[4].Risks)
+	assert.Equal(t, []string{"ci"}, info.Rules[5].Users)
+	assert.Equal(t, []SudoersCommand{{Command: "ALL", RunAs: "ALL", Tags: []string{"NOPASSWD"}}}, info.Rules[5].Commands)
+	assert.Equal(t, []string{SudoersRiskAllCommands, SudoersRiskNoPasswdAll}, info.Rules[5].Risks)
+	assert.Equal(t, []string{"ops", "deploy"}, info.Rules[6].Users)
+	assert.Len(t, info.Rules[6].Commands, 4)
+	assert.Equal(t, []string{SudoersRiskWildcardArgs}, info.Rules[6].Risks)
+	assert.Equal(t, []string{SudoersRiskWildcardCommand}, info.Rules[7].Risks)
+}
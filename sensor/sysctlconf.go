@@ -0,0 +1,181 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	sysctlConfFileName = "/etc/sysctl.conf"
+
+	// the number of symlinks which `resolveHostLink` follows, as MAXSYMLINKS of the kernel
+	hostLinkMaxDepth = 40
+)
+
+// sysctl.d directories, by precedence: a file overrides the files with the same name in the next directories
+var sysctlConfDirs = []string{
+	"/etc/sysctl.d",
+	"/run/sysctl.d",
+	"/usr/local/lib/sysctl.d",
+	"/usr/lib/sysctl.d",
+	"/lib/sysctl.d",
+}
+
+// PersistedSysctl holds the effective persisted value of a sysctl
+type PersistedSysctl struct {
+	// Example: net.ipv4.ip_forward
+	Key   string `json:"key"`
+	Value string `json:"value"`
+
+	// The config file which sets the value
+	Path string `json:"path"`
+}
+
+// SysctlDrift holds a sysctl whose running value differs from its persisted value
+type SysctlDrift struct {
+	Key            string `json:"key"`
+	PersistedValue string `json:"persistedValue"`
+	RuntimeValue   string `json:"runtimeValue"`
+	Path           string `json:"path"`
+}
+
+// SysctlsInfo holds the persisted sysctls of the host and their drift from the running values
+type SysctlsInfo struct {
+	// The sysctl config files, in the order they are applied
+	ConfigFiles []string `json:"configFiles"`
+
+	Persisted []PersistedSysctl `json:"persisted"`
+	Drifts    []SysctlDrift     `json:"drifts"`
+}
+
+// normalizeSysctlValue returns a sysctl value with its whitespace collapsed, as `sysctl` compares values
+func normalizeSysctlValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// resolveHostLink returns the path in the host file system at `rootDir` which `filePath` links to, or `filePath` if it's
+// not a symlink. Absolute symlinks, e.g. /etc/sysctl.d/99-sysctl.conf -> /etc/sysctl.conf, are resolved in `rootDir`.
+// Chains of links are followed (up to `hostLinkMaxDepth`), but only the last path component is resolved:
+// a symlink in the dirs of the path is still resolved in the scanner container.
+func resolveHostLink(rootDir, filePath string) string {
+	filePath = path.Clean("/" + filePath)
+	for i := 0; i < hostLinkMaxDepth; i++ {
+		target, err := os.Readlink(path.Join(rootDir, filePath))
+		if err != nil {
+			break
+		}
+		if path.IsAbs(target) {
+			filePath = path.Clean(target)
+		} else {
+			filePath = path.Join(path.Dir(filePath), target)
+		}
+	}
+	return filePath
+}
+
+// hostFilePath returns the path of `filePath` of the host file system at `rootDir`, see `resolveHostLink`
+func hostFilePath(rootDir, filePath string) string {
+	return path.Join(rootDir, resolveHostLink(rootDir, filePath))
+}
+
+// readHostConfFile reads `filePath` of the host file system at `rootDir`, see `hostFilePath`
+func readHostConfFile(rootDir, filePath string) ([]byte, error) {
+	return os.ReadFile(hostFilePath(rootDir, filePath))
+}
+
+// parseSysctlConf parses a sysctl.conf file into `sysctls`, overriding keys which are already set
+func parseSysctlConf(content []byte, filePath string, sysctls map[string]*PersistedSysctl, order *[]string) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		// a leading `-` ignores failures to set the key
+		key = strings.TrimPrefix(strings.TrimSpace(key), "-")
+		key = strings.ReplaceAll(strings.Trim(key, "/"), "/", ".")
+		if _, found := sysctls[key]; !found {
+			*order = append(*order, key)
+		}
+		sysctls[key] = &PersistedSysctl{Key: key, Value: normalizeSysctlValue(value), Path: filePath}
+	}
+}
+
+// senseSysctls reads the persisted sysctls of the host file system at `rootDir`, and compares them with the
+// running values under `procSys` (e.g. /proc/sys)
+func senseSysctls(ctx context.Context, rootDir, procSys string) *SysctlsInfo {
+	ret := SysctlsInfo{
+		ConfigFiles: make([]string, 0),
+		Persisted:   make([]PersistedSysctl, 0),
+		Drifts:      make([]SysctlDrift, 0),
+	}
+
+	// /etc/sysctl.conf is applied last, as `sysctl --system` does
+	confFiles := append(overlayConfFiles(rootDir, sysctlConfDirs, ".conf"), sysctlConfFileName)
+
+	sysctls := map[string]*PersistedSysctl{}
+	var order []string
+	for _, confFile := range confFiles {
+		content, err := readHostConfFile(rootDir, confFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logger.L().Ctx(ctx).Warning("failed to read sysctl config file", helpers.String("path", confFile), helpers.Error(err))
+			}
+			continue
+		}
+		ret.ConfigFiles = append(ret.ConfigFiles, confFile)
+		parseSysctlConf(content, confFile, sysctls, &order)
+	}
+
+	for _, key := range order {
+		persisted := sysctls[key]
+		ret.Persisted = append(ret.Persisted, *persisted)
+
+		// globs, e.g. net.ipv4.conf.*.rp_filter, are not compared
+		if strings.ContainsAny(key, "*?[") {
+			continue
+		}
+		runtimeValue, err := os.ReadFile(path.Join(procSys, sysctlPath(key)))
+		if err != nil {
+			continue
+		}
+		if normalized := normalizeSysctlValue(string(runtimeValue)); normalized != persisted.Value {
+			ret.Drifts = append(ret.Drifts, SysctlDrift{
+				Key:            key,
+				PersistedValue: persisted.Value,
+				RuntimeValue:   normalized,
+				Path:           persisted.Path,
+			})
+		}
+	}
+
+	return &ret
+}
+
+// SenseSysctls returns the persisted sysctls of /etc/sysctl.conf and the sysctl.d directories in precedence order,
+// and the sysctls whose running value differs from the persisted one
+func SenseSysctls(ctx context.Context) (*SysctlsInfo, error) {
+	var ret *SysctlsInfo
+	err := utils.RunInNetNs(hostNetNsProcPath("ns", "net"), func() error {
+		ret = senseSysctls(ctx, utils.HostFileSystemDefaultLocation, procSysDir)
+		return nil
+	})
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseSysctls failed to join host network namespace, net variables are of the pod network namespace",
+			helpers.Error(err))
+		ret = senseSysctls(ctx, utils.HostFileSystemDefaultLocation, procSysDir)
+	}
+	return ret, nil
+}
@@ -0,0 +1,57 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_senseSysctls(t *testing.T) {
+	info := senseSysctls(context.TODO(), "testdata/sysctl/root", "testdata/sysctl/proc/sys")
+
+	assert.Equal(t, []string{
+		"/etc/sysctl.d/10-network.conf",
+		"/usr/lib/sysctl.d/50-default.conf",
+		"/run/sysctl.d/60-vm.conf",
+		"/etc/sysctl.d/99-sysctl.conf",
+		"/etc/sysctl.conf",
+	}, info.ConfigFiles)
+
+	assert.Equal(t, []PersistedSysctl{
+		{Key: "net.ipv4.ip_forward", Value: "1", Path: "/etc/sysctl.d/10-network.conf"},
+		{Key: "fs.protected_symlinks", Value: "1", Path: "/usr/lib/sysctl.d/50-default.conf"},
+		{Key: "net.ipv4.conf.*.rp_filter", Value: "2", Path: "/usr/lib/sysctl.d/50-default.conf"},
+		{Key: "kernel.randomize_va_space", Value: "2", Path: "/usr/lib/sysctl.d/50-default.conf"},
+		{Key: "vm.overcommit_memory", Value: "1", Path: "/run/sysctl.d/60-vm.conf"},
+		{Key: "net.ipv4.conf.all.rp_filter", Value: "1", Path: "/etc/sysctl.conf"},
+	}, info.Persisted)
+
+	assert.Equal(t, []SysctlDrift{
+		{Key: "vm.overcommit_memory", PersistedValue: "1", RuntimeValue: "0", Path: "/run/sysctl.d/60-vm.conf"},
+		{Key: "net.ipv4.conf.all.rp_filter", PersistedValue: "1", RuntimeValue: "2", Path: "/etc/sysctl.conf"},
+	}, info.Drifts)
+}
+
+func Test_resolveHostLink(t *testing.T) {
+	rootDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "etc/sysctl.d"), 0o755))
+	require.NoError(t, os.MkdirAll(path.Join(rootDir, "usr/lib/sysctl.d"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(rootDir, "usr/lib/sysctl.d/50-default.conf"), []byte("vm.swappiness = 10\n"), 0o644))
+	// an absolute link to a relative link
+	require.NoError(t, os.Symlink("/usr/lib/sysctl.d/99-sysctl.conf", path.Join(rootDir, "etc/sysctl.d/50-default.conf")))
+	require.NoError(t, os.Symlink("50-default.conf", path.Join(rootDir, "usr/lib/sysctl.d/99-sysctl.conf")))
+	// a loop
+	require.NoError(t, os.Symlink("loop.conf", path.Join(rootDir, "etc/sysctl.d/loop.conf")))
+
+	assert.Equal(t, "/usr/lib/sysctl.d/50-default.conf", resolveHostLink(rootDir, "/etc/sysctl.d/50-default.conf"))
+	assert.Equal(t, "/etc/sysctl.d/other.conf", resolveHostLink(rootDir, "/etc/sysctl.d/other.conf"))
+	assert.Equal(t, "/etc/sysctl.d/loop.conf", resolveHostLink(rootDir, "/etc/sysctl.d/loop.conf"))
+
+	content, err := readHostConfFile(rootDir, "/etc/sysctl.d/50-default.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "vm.swappiness = 10\n", string(content))
+}
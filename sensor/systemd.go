@@ -0,0 +1,297 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+)
+
+const (
+	SystemdUnitsSourceDBus  = "dbus"
+	SystemdUnitsSourceFiles = "files"
+
+	systemdActiveStateFailed = "failed"
+)
+
+// The systemd unit dirs, by order of precedence
+var systemdUnitDirs = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+var systemdUnitSuffixes = []string{
+	".service", ".socket", ".timer", ".path", ".mount", ".automount", ".swap", ".target", ".slice",
+}
+
+// SystemdServiceSecurity holds the security directives of a service unit, after applying its drop-ins.
+// Empty directives are not set, so the systemd defaults apply.
+type SystemdServiceSecurity struct {
+	// The user the service runs as (root by default)
+	User string `json:"user,omitempty"`
+
+	// Example: yes
+	NoNewPrivileges string `json:"noNewPrivileges,omitempty"`
+
+	// Example: strict, full, yes
+	ProtectSystem string `json:"protectSystem,omitempty"`
+
+	// Example: ["CAP_NET_BIND_SERVICE CAP_CHOWN"], ["~CAP_SYS_ADMIN"]
+	CapabilityBoundingSet []string `json:"capabilityBoundingSet,omitempty"`
+}
+
+// SystemdUnit holds a systemd unit
+type SystemdUnit struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// The load, active and sub states. Only reported by systemd, and empty for unit files which are not loaded.
+	// Example: loaded, active, running
+	LoadState   string `json:"loadState,omitempty"`
+	ActiveState string `json:"activeState,omitempty"`
+	SubState    string `json:"subState,omitempty"`
+
+	// enabled, disabled, static, masked, alias, etc.
+	UnitFileState string `json:"unitFileState,omitempty"`
+
+	// The unit file and its drop-in files
+	FragmentPath string   `json:"fragmentPath,omitempty"`
+	DropInPaths  []string `json:"dropInPaths,omitempty"`
+
+	// Service units only
+	Security *SystemdServiceSecurity `json:"security,omitempty"`
+}
+
+// SystemdUnitsInfo holds the systemd units of the host
+type SystemdUnitsInfo struct {
+	// dbus if the units were listed by systemd, or files if they were read from the unit dirs
+	Source string `json:"source"`
+
+	Units []SystemdUnit `json:"units"`
+
+	// The names of the units in the failed state
+	FailedUnits []string `json:"failedUnits"`
+}
+
+// systemdUnitFile holds the directives of a unit file and its drop-ins, by section and key, in order
+type systemdUnitFile map[string]map[string][]string
+
+// parseSystemdUnitFile parses the content of a unit or drop-in file into `unitFile`
+func parseSystemdUnitFile(content []byte, unitFile systemdUnitFile) {
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	continued := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if continued == "" && (line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";")) {
+			continue
+		}
+		// a trailing backslash continues the line
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " "
+			continue
+		}
+		line, continued = continued+line, ""
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == "" {
+			continue
+		}
+		if unitFile[section] == nil {
+			unitFile[section] = map[string][]string{}
+		}
+		key = strings.TrimSpace(key)
+		unitFile[section][key] = append(unitFile[section][key], strings.TrimSpace(value))
+	}
+}
+
+// value returns the last value of a directive
+func (unitFile systemdUnitFile) value(section, key string) string {
+	values := unitFile[section][key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// values returns the values of a list directive. An empty value resets the list.
+func (unitFile systemdUnitFile) values(section, key string) []string {
+	var res []string
+	for _, value := range unitFile[section][key] {
+		if value == "" {
+			res = nil
+			continue
+		}
+		res = append(res, value)
+	}
+	return res
+}
+
+// readSystemdUnitFile reads a unit file of the host file system at `rootDir`, and then its drop-ins
+func readSystemdUnitFile(rootDir, fragmentPath string, dropInPaths []string) (systemdUnitFile, error) {
+	unitFile := systemdUnitFile{}
+	content, err := readHostConfFile(rootDir, fragmentPath)
+	if err != nil {
+		return nil, err
+	}
+	parseSystemdUnitFile(content, unitFile)
+	for _, dropInPath := range dropInPaths {
+		if content, err := readHostConfFile(rootDir, dropInPath); err == nil {
+			parseSystemdUnitFile(content, unitFile)
+		}
+	}
+	return unitFile, nil
+}
+
+// systemdDropInPaths returns the `<unit>.d/*.conf` drop-ins of a unit in the unit dirs
+func systemdDropInPaths(rootDir, name string) []string {
+	dirs := make([]string, 0, len(systemdUnitDirs))
+	for _, dir := range systemdUnitDirs {
+		dirs = append(dirs, path.Join(dir, name+".d"))
+	}
+	return overlayConfFiles(rootDir, dirs, ".conf")
+}
+
+// systemdWantedUnits returns the units linked from the .wants and .requires dirs of /etc/systemd/system, i.e. enabled units
+func systemdWantedUnits(rootDir string) map[string]bool {
+	wanted := map[string]bool{}
+	adminDir := path.Join(rootDir, systemdUnitDirs[0])
+	entries, err := os.ReadDir(adminDir)
+	if err != nil {
+		return wanted
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !(strings.HasSuffix(entry.Name(), ".wants") || strings.HasSuffix(entry.Name(), ".requires")) {
+			continue
+		}
+		links, err := os.ReadDir(path.Join(adminDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		for _, link := range links {
+			wanted[link.Name()] = true
+			// an enabled instance, e.g. getty@tty1.service, enables its template
+			if prefix, suffix, isInstance := strings.Cut(link.Name(), "@"); isInstance {
+				wanted[prefix+"@"+path.Ext(suffix)] = true
+			}
+		}
+	}
+	return wanted
+}
+
+// listSystemdUnitFiles lists the unit files of the unit dirs, as `systemctl list-unit-files` does
+func listSystemdUnitFiles(rootDir string) []utils.SystemdUnitStatus {
+	wanted := systemdWantedUnits(rootDir)
+
+	var units []utils.SystemdUnitStatus
+	for _, unitPath := range overlayConfFiles(rootDir, systemdUnitDirs, "") {
+		name := path.Base(unitPath)
+		isUnit := false
+		for _, suffix := range systemdUnitSuffixes {
+			isUnit = isUnit || strings.HasSuffix(name, suffix)
+		}
+		if !isUnit {
+			continue
+		}
+
+		unit := utils.SystemdUnitStatus{Name: name, FragmentPath: unitPath}
+		if target, err := os.Readlink(path.Join(rootDir, unitPath)); err == nil {
+			switch {
+			case target == "/dev/null":
+				unit.UnitFileState = "masked"
+				units = append(units, unit)
+				continue
+			case path.Base(target) != name:
+				unit.UnitFileState = "alias"
+			}
+		}
+
+		unitFile, err := readSystemdUnitFile(rootDir, unitPath, nil)
+		if err != nil {
+			continue
+		}
+		if unit.UnitFileState == "" {
+			switch {
+			case wanted[name]:
+				unit.UnitFileState = "enabled"
+			case len(unitFile["Install"]) == 0:
+				unit.UnitFileState = "static"
+			default:
+				unit.UnitFileState = "disabled"
+			}
+		}
+		unit.Description = unitFile.value("Unit", "Description")
+		unit.DropInPaths = systemdDropInPaths(rootDir, name)
+		units = append(units, unit)
+	}
+	return units
+}
+
+// systemdServiceSecurity returns the security directives of a service unit
+func systemdServiceSecurity(unitFile systemdUnitFile) *SystemdServiceSecurity {
+	if _, isService := unitFile["Service"]; !isService {
+		return nil
+	}
+	return &SystemdServiceSecurity{
+		User:                  unitFile.value("Service", "User"),
+		NoNewPrivileges:       unitFile.value("Service", "NoNewPrivileges"),
+		ProtectSystem:         unitFile.value("Service", "ProtectSystem"),
+		CapabilityBoundingSet: unitFile.values("Service", "CapabilityBoundingSet"),
+	}
+}
+
+func senseSystemdUnits(ctx context.Context, rootDir string, listUnits func(context.Context) ([]utils.SystemdUnitStatus, error)) (*SystemdUnitsInfo, error) {
+	info := SystemdUnitsInfo{Source: SystemdUnitsSourceDBus, Units: []SystemdUnit{}, FailedUnits: []string{}}
+
+	unitStatuses, err := listUnits(ctx)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to list systemd units over D-Bus, reading the unit files", helpers.Error(err))
+		info.Source = SystemdUnitsSourceFiles
+		unitStatuses = listSystemdUnitFiles(rootDir)
+	}
+
+	for _, unitStatus := range unitStatuses {
+		unit := SystemdUnit{
+			Name:          unitStatus.Name,
+			Description:   unitStatus.Description,
+			LoadState:     unitStatus.LoadState,
+			ActiveState:   unitStatus.ActiveState,
+			SubState:      unitStatus.SubState,
+			UnitFileState: unitStatus.UnitFileState,
+			FragmentPath:  unitStatus.FragmentPath,
+			DropInPaths:   unitStatus.DropInPaths,
+		}
+		if unit.FragmentPath != "" && unit.UnitFileState != "masked" {
+			unitFile, err := readSystemdUnitFile(rootDir, unit.FragmentPath, unit.DropInPaths)
+			if err != nil {
+				logger.L().Ctx(ctx).Debug("failed to read unit file", helpers.String("path", unit.FragmentPath), helpers.Error(err))
+			} else {
+				unit.Security = systemdServiceSecurity(unitFile)
+			}
+		}
+		if unit.ActiveState == systemdActiveStateFailed {
+			info.FailedUnits = append(info.FailedUnits, unit.Name)
+		}
+		info.Units = append(info.Units, unit)
+	}
+	return &info, nil
+}
+
+// SenseSystemdUnits returns the systemd units with their states and the security directives of the services.
+// The units are listed over D-Bus, falling back to the unit files on disk.
+func SenseSystemdUnits(ctx context.Context) (*SystemdUnitsInfo, error) {
+	return senseSystemdUnits(ctx, utils.HostFileSystemDefaultLocation, utils.ListSystemdUnits)
+}
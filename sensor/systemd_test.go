@@ -0,0 +1,91 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kubescape/host-scanner/sensor/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSystemdUnitFile(t *testing.T) {
+	unitFile := systemdUnitFile{}
+	parseSystemdUnitFile([]byte("# comment\n[Service]\nExecStart=/bin/a \\\n  --flag\n; comment\nUser = nobody\nUser=\n"), unitFile)
+
+	assert.Equal(t, "/bin/a --flag", unitFile.value("Service", "ExecStart"))
+	// an empty value resets the directive
+	assert.Equal(t, "", unitFile.value("Service", "User"))
+	assert.Empty(t, unitFile.values("Service", "User"))
+	assert.Equal(t, "", unitFile.value("Install", "WantedBy"))
+}
+
+func Test_senseSystemdUnitsDBus(t *testing.T) {
+	listUnits := func(context.Context) ([]utils.SystemdUnitStatus, error) {
+		return []utils.SystemdUnitStatus{
+			{
+				Name: "kubelet.service", Description: "kubelet: The Kubernetes Node Agent", LoadState: "loaded", ActiveState: "active", SubState: "running",
+				UnitFileState: "enabled", FragmentPath: "/etc/systemd/system/kubelet.service",
+				DropInPaths: []string{"/etc/systemd/system/kubelet.service.d/10-kubeadm.conf"},
+			},
+			{Name: "chronyd.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed", FragmentPath: "/lib/systemd/system/chronyd.service"},
+			{Name: "systemd-journald.socket", LoadState: "loaded", ActiveState: "active", SubState: "running", FragmentPath: "/lib/systemd/system/systemd-journald.socket"},
+			{Name: "missing.service", LoadState: "not-found", ActiveState: "inactive", SubState: "dead"},
+		}, nil
+	}
+	info, err := senseSystemdUnits(context.TODO(), "testdata/systemd/root", listUnits)
+	require.NoError(t, err)
+
+	assert.Equal(t, SystemdUnitsSourceDBus, info.Source)
+	assert.Equal(t, []string{"chronyd.service"}, info.FailedUnits)
+	require.Len(t, info.Units, 4)
+
+	// the drop-in overrides the unit file
+	assert.Equal(t, &SystemdServiceSecurity{User: "root", NoNewPrivileges: "yes", ProtectSystem: "full"}, info.Units[0].Security)
+	assert.Equal(t, &SystemdServiceSecurity{User: "chrony", NoNewPrivileges: "yes", ProtectSystem: "strict", CapabilityBoundingSet: []string{"CAP_SYS_TIME"}},
+		info.Units[1].Security)
+	assert.Nil(t, info.Units[2].Security)
+	assert.Nil(t, info.Units[3].Security)
+}
+
+func Test_senseSystemdUnitsFiles(t *testing.T) {
+	listUnits := func(context.Context) ([]utils.SystemdUnitStatus, error) {
+		return nil, fmt.Errorf("no D-Bus")
+	}
+	info, err := senseSystemdUnits(context.TODO(), "testdata/systemd/root", listUnits)
+	require.NoError(t, err)
+
+	assert.Equal(t, SystemdUnitsSourceFiles, info.Source)
+	assert.Empty(t, info.FailedUnits)
+
+	units := map[string]SystemdUnit{}
+	var names []string
+	for _, unit := range info.Units {
+		units[unit.Name] = unit
+		names = append(names, unit.Name)
+	}
+	assert.Equal(t, []string{"chrony.service", "chronyd.service", "containerd.service", "getty@.service", "kubelet.service",
+		"systemd-journald.socket", "telnet.socket"}, names)
+
+	assert.Equal(t, "alias", units["chrony.service"].UnitFileState)
+	assert.Equal(t, "disabled", units["chronyd.service"].UnitFileState)
+	assert.Equal(t, "enabled", units["getty@.service"].UnitFileState)
+	assert.Equal(t, "static", units["systemd-journald.socket"].UnitFileState)
+	assert.Equal(t, "Journal Socket", units["systemd-journald.socket"].Description)
+
+	// telnet.socket is masked in /etc/systemd/system
+	assert.Equal(t, SystemdUnit{Name: "telnet.socket", UnitFileState: "masked", FragmentPath: "/etc/systemd/system/telnet.socket"}, units["telnet.socket"])
+
+	assert.Equal(t, SystemdUnit{
+		Name:          "containerd.service",
+		Description:   "containerd container runtime",
+		UnitFileState: "enabled",
+		FragmentPath:  "/lib/systemd/system/containerd.service",
+		DropInPaths:   []string{"/usr/lib/systemd/system/containerd.service.d/10-caps.conf"},
+		Security:      &SystemdServiceSecurity{CapabilityBoundingSet: []string{"CAP_SYS_ADMIN CAP_NET_ADMIN", "CAP_CHOWN"}},
+	}, units["containerd.service"])
+
+	assert.Equal(t, "enabled", units["kubelet.service"].UnitFileState)
+	assert.Equal(t, "yes", units["kubelet.service"].Security.NoNewPrivileges)
+}
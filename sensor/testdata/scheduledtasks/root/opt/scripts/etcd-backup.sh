@@ -0,0 +1,2 @@
+#!/bin/sh
+etcdctl snapshot save "$1"
@@ -0,0 +1,207 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/weaveworks/procspy"
+)
+
+const (
+	// path relative to `/proc/<pid>`
+	procNetUnixPath = "net/unix"
+
+	// __SO_ACCEPTCON flag of /proc/net/unix, set for listening sockets
+	unixSocketAcceptConFlag = 0x10000
+
+	// Unix sockets risks
+	UnixSocketRiskContainerRuntime = "containerRuntimeSocket"
+	UnixSocketRiskWorldWritable    = "worldWritable"
+	UnixSocketRiskGroupWritable    = "groupWritable"
+)
+
+// Well known sockets which grant control over the node to whoever can connect them
+var escalationUnixSockets = []string{
+	"/containerd.sock",
+	"/crio.sock",
+	"/docker.sock",
+	"/cri-dockerd.sock",
+	"/dockershim.sock",
+	"/podman.sock",
+	"/buildkitd.sock",
+}
+
+var unixSocketTypes = map[string]string{
+	"0001": "stream",
+	"0002": "dgram",
+	"0005": "seqpacket",
+}
+
+// UnixSocket holds information about a listening UNIX domain socket
+type UnixSocket struct {
+	// The socket path. Abstract sockets are prefixed with `@`.
+	// Example: /run/containerd/containerd.sock
+	Path string `json:"path"`
+
+	// The socket type (stream, dgram or seqpacket)
+	Type string `json:"type"`
+
+	Inode uint64 `json:"inode"`
+
+	// Information about the socket file on the host file system (if not abstract)
+	File *ds.FileInfo `json:"file,omitempty"`
+
+	// The process owning the socket (if found)
+	Process *procspy.Proc `json:"process,omitempty"`
+
+	// Known escalation risks of the socket
+	// Example: ["containerRuntimeSocket", "worldWritable"]
+	Risks []string `json:"risks,omitempty"`
+}
+
+// UnixSocketsInfo holds the listening UNIX domain sockets of the host
+type UnixSocketsInfo struct {
+	Sockets []UnixSocket `json:"sockets"`
+}
+
+// parseProcNetUnix parses the content of /proc/net/unix and returns the listening sockets
+func parseProcNetUnix(content []byte) []UnixSocket {
+	res := make([]UnixSocket, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	// skip header
+	scanner.Scan()
+	for scanner.Scan() {
+		// Num RefCount Protocol Flags Type St Inode [Path]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&unixSocketAcceptConFlag == 0 {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil {
+			continue
+		}
+		socket := UnixSocket{
+			Type:  unixSocketTypes[fields[4]],
+			Inode: inode,
+		}
+		if len(fields) > 7 {
+			socket.Path = strings.Join(fields[7:], " ")
+		}
+		res = append(res, socket)
+	}
+	return res
+}
+
+// socketOwners walks on `/proc/*/fd` and returns a map from socket inode to the owning process
+func socketOwners(procDir string) (map[uint64]procspy.Proc, error) {
+	pidDirs, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processes dir: %w", err)
+	}
+
+	res := map[uint64]procspy.Proc{}
+	for _, pidDir := range pidDirs {
+		// since processes are about to die in the middle of the loop, we will ignore next errors
+		pid, err := strconv.ParseUint(pidDir.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		fdDir := path.Join(procDir, pidDir.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		name := ""
+		for _, fd := range fds {
+			link, err := os.Readlink(path.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if name == "" {
+				comm, _ := os.ReadFile(path.Join(procDir, pidDir.Name(), "comm"))
+				name = strings.TrimSpace(string(comm))
+			}
+			// keep the first (lowest pid) owner of a shared socket
+			if _, ok := res[inode]; !ok {
+				res[inode] = procspy.Proc{PID: uint(pid), Name: name}
+			}
+		}
+	}
+	return res, nil
+}
+
+// unixSocketRisks returns the known escalation risks of a socket
+func unixSocketRisks(socket *UnixSocket) []string {
+	var risks []string
+	isRuntimeSocket := false
+	for _, suffix := range escalationUnixSockets {
+		if strings.HasSuffix(socket.Path, suffix) {
+			isRuntimeSocket = true
+			risks = append(risks, UnixSocketRiskContainerRuntime)
+			break
+		}
+	}
+	if socket.File == nil {
+		return risks
+	}
+	if socket.File.Permissions&0o002 != 0 {
+		risks = append(risks, UnixSocketRiskWorldWritable)
+	}
+	// a runtime socket writable by a non root group (e.g. `docker`) grants root to the group members
+	if isRuntimeSocket && socket.File.Permissions&0o020 != 0 &&
+		socket.File.Ownership != nil && socket.File.Ownership.GID != 0 {
+		risks = append(risks, UnixSocketRiskGroupWritable)
+	}
+	return risks
+}
+
+// SenseUnixSockets returns the listening UNIX domain sockets of the host
+func SenseUnixSockets(ctx context.Context) (*UnixSocketsInfo, error) {
+	ret := UnixSocketsInfo{}
+
+	content, err := os.ReadFile(hostNetNsProcPath(procNetUnixPath))
+	if err != nil {
+		return &ret, fmt.Errorf("failed to read unix sockets: %w", err)
+	}
+	ret.Sockets = parseProcNetUnix(content)
+
+	owners, err := socketOwners(procDirName)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("In SenseUnixSockets failed to find sockets owners", helpers.Error(err))
+	}
+
+	for i := range ret.Sockets {
+		socket := &ret.Sockets[i]
+		if proc, ok := owners[socket.Inode]; ok {
+			socket.Process = &proc
+		}
+		if socket.Path != "" && !strings.HasPrefix(socket.Path, "@") {
+			socket.File = makeHostFileInfoVerbose(ctx, socket.Path, false,
+				helpers.String("in", "SenseUnixSockets"),
+			)
+		}
+		socket.Risks = unixSocketRisks(socket)
+	}
+
+	sort.Slice(ret.Sockets, func(i, j int) bool { return ret.Sockets[i].Path < ret.Sockets[j].Path })
+
+	return &ret, nil
+}
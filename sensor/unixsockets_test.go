@@ -0,0 +1,83 @@
+package sensor
+
+import (
+	"os"
+	"testing"
+
+	ds "github.com/kubescape/host-scanner/sensor/datastructures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/procspy"
+)
+
+func Test_parseProcNetUnix(t *testing.T) {
+	content, err := os.ReadFile("testdata/proc/1/net/unix")
+	require.NoError(t, err)
+
+	sockets := parseProcNetUnix(content)
+	assert.Equal(t, []UnixSocket{
+		{Path: "/run/containerd/containerd.sock", Type: "stream", Inode: 21001},
+		{Path: "/var/run/docker.sock", Type: "stream", Inode: 21002},
+		{Path: "@/org/kernel/linux/storage/multipathd", Type: "seqpacket", Inode: 21003},
+	}, sockets)
+}
+
+func Test_socketOwners(t *testing.T) {
+	owners, err := socketOwners("testdata/proc")
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]procspy.Proc{
+		21001: {PID: 42, Name: "containerd"},
+		21002: {PID: 1, Name: "systemd"},
+	}, owners)
+}
+
+func Test_unixSocketRisks(t *testing.T) {
+	tests := []struct {
+		name   string
+		socket UnixSocket
+		want   []string
+	}{
+		{
+			name: "runtime socket owned by root",
+			socket: UnixSocket{
+				Path: "/run/containerd/containerd.sock",
+				File: &ds.FileInfo{Permissions: 0o660, Ownership: &ds.FileOwnership{}},
+			},
+			want: []string{UnixSocketRiskContainerRuntime},
+		},
+		{
+			name: "world writable runtime socket",
+			socket: UnixSocket{
+				Path: "/var/run/docker.sock",
+				File: &ds.FileInfo{Permissions: 0o666, Ownership: &ds.FileOwnership{}},
+			},
+			want: []string{UnixSocketRiskContainerRuntime, UnixSocketRiskWorldWritable},
+		},
+		{
+			name: "runtime socket writable by docker group",
+			socket: UnixSocket{
+				Path: "/var/run/docker.sock",
+				File: &ds.FileInfo{Permissions: 0o660, Ownership: &ds.FileOwnership{GID: 999}},
+			},
+			want: []string{UnixSocketRiskContainerRuntime, UnixSocketRiskGroupWritable},
+		},
+		{
+			name: "other socket",
+			socket: UnixSocket{
+				Path: "/run/systemd/private",
+				File: &ds.FileInfo{Permissions: 0o600, Ownership: &ds.FileOwnership{}},
+			},
+			want: nil,
+		},
+		{
+			name:   "abstract runtime socket",
+			socket: UnixSocket{Path: "@/containerd-shim/abc.sock"},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, unixSocketRisks(&tt.socket))
+		})
+	}
+}